@@ -306,26 +306,27 @@ func SendHTTPRequest(method, path string, headers map[string]string, body io.Rea
 // SendHTTPRequest2 sends an HTTP request.
 // Returns the response body and status code, or an error.
 func SendHTTPRequest2(method, path string, headers http.Header, body io.Reader) (string, int, error) {
+	contents, _, statusCode, err := SendHTTPRequestWithHeaders(method, path, headers, body)
+	return contents, statusCode, err
+}
+
+// SendHTTPRequestWithHeaders sends an HTTP request.
+// Returns the response body, headers, and status code, or an error.
+func SendHTTPRequestWithHeaders(method, path string, headers http.Header, body io.Reader) (string, http.Header, int, error) {
 	upperMethod := strings.ToUpper(method)
 
-	if upperMethod != "POST" && upperMethod != "GET" && upperMethod != "DELETE" {
-		return "", 0, errors.New("invalid HTTP method specified")
+	if upperMethod != "POST" && upperMethod != "GET" && upperMethod != "DELETE" && upperMethod != "PUT" {
+		return "", nil, 0, errors.New("invalid HTTP method specified")
 	}
 
 	req, err := http.NewRequest(upperMethod, path, body)
 
 	if err != nil {
-		return "", 0, err
+		return "", nil, 0, err
 	}
 
 	req.Header = headers
 
-	requestDump, err := httputil.DumpRequest(req, true)
-	if err != nil {
-		fmt.Println(err)
-	}
-	fmt.Println(string(requestDump))
-
 	timeout := time.Duration(3 * time.Second)
 	if upperMethod == "POST" {
 		timeout = time.Duration(15 * time.Second)
@@ -334,17 +335,17 @@ func SendHTTPRequest2(method, path string, headers http.Header, body io.Reader)
 	resp, err := httpClient.Do(req)
 
 	if err != nil {
-		return "", 0, err
+		return "", nil, 0, err
 	}
 
 	contents, err := ioutil.ReadAll(resp.Body)
 	defer resp.Body.Close()
 
 	if err != nil {
-		return "", 0, err
+		return "", nil, 0, err
 	}
 
-	return string(contents), resp.StatusCode, nil
+	return string(contents), resp.Header, resp.StatusCode, nil
 }
 
 // SendHTTPGetRequest sends a simple get request using a url string & JSON
@@ -74,7 +74,9 @@ func (a *Alphapoint) UpdateOrderbook(p pair.CurrencyPair, assetType string) (ord
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: data.Quantity, Price: data.Price})
 	}
 
-	a.Orderbooks.ProcessOrderbook(a.GetName(), p, orderBook, assetType)
+	if err := a.Orderbooks.ProcessOrderbook(a.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return a.Orderbooks.GetOrderbook(a.Name, p, assetType)
 }
 
@@ -1,32 +1,172 @@
 package binance
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mattkanwisher/cryptofiend/common"
 	"github.com/mattkanwisher/cryptofiend/currency/pair"
 	exchange "github.com/mattkanwisher/cryptofiend/exchanges"
+	"github.com/mattkanwisher/cryptofiend/exchanges/orderbook"
+	"github.com/shopspring/decimal"
 )
 
 const (
-	binanceBaseURL          = "https://www.binance.com/"
-	binanceExchangeInfoPath = "api/v1/exchangeInfo"
-	binanceAccountPath      = "api/v3/account"
-	binanceOpenOrdersPath   = "api/v3/openOrders"
-	binanceOrderPath        = "api/v3/order"
-	binanceOrderTestPath    = "api/v3/order/test"
-	binanceDepthPath        = "api/v1/depth"
+	binanceBaseURL = "https://www.binance.com/"
+
+	// Endpoints are grouped under separately versioned path roots: api/ for spot trading &
+	// market data, sapi/ for margin, wallet & account management, wapi/ for withdrawals, and
+	// fapi/ for futures. Every endpoint path includes its root and is relative to the API URL,
+	// the root makes no difference to how a request is sent or signed.
+	binanceAPIv1Root  = "api/v1/"
+	binanceAPIv3Root  = "api/v3/"
+	binanceSAPIv1Root = "sapi/v1/"
+
+	binanceExchangeInfoPath       = binanceAPIv3Root + "exchangeInfo"
+	binanceAccountPath            = binanceAPIv3Root + "account"
+	binanceOpenOrdersPath         = binanceAPIv3Root + "openOrders"
+	binanceOrderPath              = binanceAPIv3Root + "order"
+	binanceOrderTestPath          = binanceAPIv3Root + "order/test"
+	binanceDepthPath              = binanceAPIv3Root + "depth"
+	binanceOrderListPath          = binanceAPIv3Root + "orderList"
+	binanceOpenOrderListPath      = binanceAPIv3Root + "openOrderList"
+	binanceUserDataStreamPath     = binanceAPIv3Root + "userDataStream"
+	binanceAllCoinsInfoPath       = binanceSAPIv1Root + "capital/config/getall"
+	binanceSystemStatusPath       = binanceSAPIv1Root + "system/status"
+	binanceServerTimePath         = binanceAPIv1Root + "time"
+	binanceAllOrdersPath          = binanceAPIv3Root + "allOrders"
+	binanceMyTradesPath           = binanceAPIv3Root + "myTrades"
+	binanceAPIRestrictionsPath    = binanceSAPIv1Root + "account/apiRestrictions"
+	binanceIPRestrictionPath      = binanceSAPIv1Root + "account/apiRestrictions/ipRestriction"
+	binanceMarginAccountPath      = binanceSAPIv1Root + "margin/account"
+	binanceMaxBorrowablePath      = binanceSAPIv1Root + "margin/maxBorrowable"
+	binanceTickerPricePath        = binanceAPIv3Root + "ticker/price"
+	binanceTicker24hPath          = binanceAPIv3Root + "ticker/24hr"
+	binanceRecentTradesPath       = binanceAPIv3Root + "trades"
+	binanceAssetDividendPath      = binanceSAPIv1Root + "asset/assetDividend"
+	binanceBNBBurnPath            = binanceSAPIv1Root + "bnbBurn"
+	binancePingPath               = binanceAPIv3Root + "ping"
+	binanceOrderRateLimitPath     = binanceAPIv3Root + "rateLimit/order"
+	binanceConvertGetQuotePath    = binanceSAPIv1Root + "convert/getQuote"
+	binancePreventedMatchesPath   = binanceAPIv3Root + "myPreventedMatches"
+	binanceMyAllocationsPath      = binanceAPIv3Root + "myAllocations"
+	binanceConvertAcceptQuotePath = binanceSAPIv1Root + "convert/acceptQuote"
+
+	// Max length of the response body snippet included in ErrExchangeUnavailable.
+	maxErrorSnippetLength = 128
+	// Max request weight that can be used per minute before Binance starts rejecting requests.
+	binanceRequestWeightPerMin = 1200
+	// How long to stop sending requests after being rate limited if Binance doesn't specify.
+	defaultRateLimitCooldown = 2 * time.Minute
+	// Max time range that can be queried in a single asset dividend request.
+	maxAssetDividendRange = 90 * 24 * time.Hour
+	// Max length of a client order ID.
+	maxClientOrderIDLength = 36
+	// Min value of an order strategy type, lower values are reserved by Binance.
+	minStrategyType = 1000000
+	// How many poll intervals WaitForOrder waits between fetches of an order that the live account
+	// shows is still open, in case an execution report was missed.
+	waitForOrderLivePollTicks = 10
+)
+
+var (
+	// ErrRateLimited is returned without sending a request while backing off after Binance has
+	// rate limited a previous request.
+	ErrRateLimited = errors.New("rate limited by Binance, backing off")
+	// ErrTooManyOrders is returned without placing an order if placing it would exceed one of
+	// the order rate limits obtained by LoadMarkets.
+	ErrTooManyOrders = errors.New("order rate limit reached")
+	// ErrExchangeInfoNotLoaded is returned by methods that rely on the exchange info cached by
+	// LoadMarkets if it hasn't been called yet (or hasn't succeeded).
+	ErrExchangeInfoNotLoaded = errors.New("exchange info hasn't been loaded")
+	// ErrNoOrders is returned by FetchMostRecentOrder if the account has no orders for the symbol.
+	ErrNoOrders = errors.New("no orders found")
 )
 
+// endpointWeights maps HTTP method & path to the request weight Binance charges for a request,
+// endpoints whose weight depends on the request parameters are handled by EndpointWeight.
+var endpointWeights = map[string]int{
+	http.MethodGet + binanceExchangeInfoPath:   10,
+	http.MethodGet + binanceAccountPath:        10,
+	http.MethodGet + binanceOrderPath:          2,
+	http.MethodPost + binanceOrderPath:         1,
+	http.MethodPost + binanceOrderTestPath:     1,
+	http.MethodDelete + binanceOrderPath:       1,
+	http.MethodGet + binanceOrderListPath:      2,
+	http.MethodGet + binanceOpenOrderListPath:  3,
+	http.MethodGet + binanceAllCoinsInfoPath:   10,
+	http.MethodGet + binanceAllOrdersPath:      10,
+	http.MethodGet + binanceMyTradesPath:       10,
+	http.MethodGet + binanceMarginAccountPath:  10,
+	http.MethodGet + binanceMaxBorrowablePath:  50,
+	http.MethodGet + binanceAssetDividendPath:  10,
+	http.MethodGet + binanceOrderRateLimitPath: 40,
+	http.MethodGet + binanceMyAllocationsPath:  20,
+}
+
+// EndpointWeight returns the request weight Binance charges for sending a request with the given
+// parameters to the given method & path. Unknown endpoints are assumed to have a weight of 1.
+func EndpointWeight(method, path string, params url.Values) int {
+	switch path {
+	case binanceDepthPath:
+		if params.Get("limit") == "" {
+			return 1 // default limit is 100
+		}
+		limit, _ := strconv.Atoi(params.Get("limit"))
+		switch {
+		case limit == 0: // no limit
+			return 50
+		case limit <= 100:
+			return 1
+		case limit <= 500:
+			return 5
+		case limit <= 1000:
+			return 10
+		default:
+			return 50
+		}
+	case binanceOpenOrdersPath:
+		if method == http.MethodDelete {
+			return 1
+		}
+		if params.Get("symbol") == "" {
+			return 40
+		}
+		return 3
+	case binanceTickerPricePath:
+		if params.Get("symbol") == "" {
+			return 2
+		}
+		return 1
+	case binanceTicker24hPath:
+		if params.Get("symbol") == "" {
+			return 40
+		}
+		return 1
+	case binancePreventedMatchesPath:
+		if params.Get("preventedMatchId") != "" {
+			return 2
+		}
+		return 20
+	}
+	if weight, exists := endpointWeights[method+path]; exists {
+		return weight
+	}
+	return 1
+}
+
 // BinanceErrCode enum represents a frequently encountered subset of the error codes documented at:
 // https://github.com/binance-exchange/binance-official-api-docs/blob/master/errors.md
 type BinanceErrCode int32
@@ -34,170 +174,1811 @@ type BinanceErrCode int32
 const (
 	TooManyRequestsErrCode  BinanceErrCode = -1003
 	InvalidTimestampErrCode BinanceErrCode = -1021 // fix: sync your computer clock to internet time
+	CancelRejectedErrCode   BinanceErrCode = -2011 // order doesn't exist or was already cancelled
+	NoSuchOrderErrCode      BinanceErrCode = -2013
 )
 
+// ErrExchangeUnavailable is returned when a request fails with a response that isn't a Binance API
+// error, this usually happens when the exchange is down for maintenance.
+type ErrExchangeUnavailable struct {
+	StatusCode int
+	// The beginning of the response body
+	Snippet string
+}
+
+func (e *ErrExchangeUnavailable) Error() string {
+	return fmt.Sprintf("exchange unavailable (HTTP status %d): %s", e.StatusCode, e.Snippet)
+}
+
+// Temporary returns true if the request failed due to a server error and can be retried later.
+func (e *ErrExchangeUnavailable) Temporary() bool {
+	return e.StatusCode >= 500
+}
+
+// Client covers the commonly used Binance operations, it's implemented by *Binance and can be
+// used in place of it wherever a mock may need to be injected (e.g. in tests).
+type Client interface {
+	FetchAccountInfo() (*AccountInfo, error)
+	PostOrderAck(params *PostOrderParams) (*PostOrderAckResponse, error)
+	FetchOrder(symbol string, orderID int64, clientOrderID string) (*Order, error)
+	DeleteOrder(symbol string, orderID int64, clientOrderID string) error
+	FetchOpenOrders(symbol string) ([]Order, error)
+	FetchMarketData(symbol string, limit int64) (*MarketData, error)
+}
+
+var _ Client = (*Binance)(nil)
+
+// Logger is used to output verbose logging, *log.Logger satisfies this interface.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// noopLogger is a Logger that discards everything, it's the default Binance logger.
+type noopLogger struct{}
+
+func (noopLogger) Printf(format string, v ...interface{}) {}
+
 type Binance struct {
 	exchange.Base
+	// Verbose logging is sent to this logger, request signatures and API keys are redacted.
+	Logger Logger
+	// Optional hook that's invoked after every HTTP request with the response status code (zero if
+	// no response was received), the request latency, and the request weight used up.
+	OnRequest func(method, path string, status int, latency time.Duration, weightUsed int)
+	// Optional prefix for client order IDs, if set orders placed without a client order ID are
+	// assigned one generated by GenerateClientOrderID. Should be no more than 20 characters long.
+	ClientOrderIDPrefix string
+	// Response type requested by PostOrder, determines which order details it returns. Defaults to
+	// OrderRespTypeAck if not set.
+	DefaultOrderRespType OrderRespType
+	// Optional backoff policies used when re-establishing websocket streams, and when retrying
+	// idempotent requests (GET requests & order cancellations) that failed due to network errors.
+	// DefaultStreamBackoff and DefaultRequestBackoff are used if these aren't set.
+	StreamBackoff  *BackoffPolicy
+	RequestBackoff *BackoffPolicy
+	// Incremented for every client order ID generated, must be accessed atomically
+	clientOrderIDSeq uint32
 	// Maps HTTP method & path to a timestamp (in msecs) of the last time a request was sent
 	rateLimits map[string]int64
 	// Timestamp (in msecs) of the last time the Binance server rate limited a request
 	ipBanStartTime int64
+	// Timestamp (in msecs) of the start of the current one minute request weight window,
+	// and the request weight used up within that window
+	weightWindowStart int64
+	weightUsed        int
+	// No requests will be sent until this time after Binance has rate limited a request
+	cooldownUntil time.Time
+	// Guards the request weight window & cooldown, requests may be sent from multiple goroutines
+	weightMtx sync.Mutex
+	// Offset (in nsecs) between the local clock and the Binance server clock, measured by SyncTime,
+	// must be accessed atomically
+	serverTimeOffset int64
+	// Source of the local time used to timestamp signed requests and to measure the server time
+	// offset, defaults to time.Now (tests can inject a fixed time)
+	nowFunc func() time.Time
 	// Maps symbol (exchange specific market identifier) to currency pair info
 	currencyPairs    map[pair.CurrencyItem]*exchange.CurrencyPairInfo
 	symbolDetailsMap map[pair.CurrencyItem]*symbolDetails
+	// Exchange info obtained by the last call to LoadMarkets, and the symbols in it mapped by name
+	exchangeInfo  *ExchangeInfo
+	symbolInfo    map[string]*SymbolInfo
+	symbolFilters map[string]*SymbolFilters
+	marketsMtx    sync.RWMutex
 	// Cached data that's returned when HTTP requests are rate-limited
 	lastAccountInfo AccountInfo
 	lastOpenOrders  map[string][]Order
 	lastMarketData  map[string]*MarketData
+	// Order rate limits obtained by LoadMarkets, and the times orders were placed within the
+	// longest of the limit windows (oldest first)
+	orderLimits []RateLimit
+	orderTimes  []time.Time
+	ordersMtx   sync.Mutex
+	// Account info cached by GetAccountInfo, and the time it was fetched
+	cachedAccountInfo    *AccountInfo
+	accountInfoFetchedAt time.Time
+	accountInfoMtx       sync.Mutex
+	// Account info and the IDs of the open orders of each symbol maintained by StartLiveAccount
+	liveAccount    *AccountInfo
+	liveOpenOrders map[string]map[int64]struct{}
+	liveAccountMtx sync.Mutex
+}
+
+// CurrencyPairToSymbol converts a currency pair to a symbol (exchange specific market identifier).
+func (b *Binance) CurrencyPairToSymbol(p pair.CurrencyPair) string {
+	return p.
+		Display(b.RequestCurrencyPairFormat.Delimiter, b.RequestCurrencyPairFormat.Uppercase).
+		String()
+}
+
+// SymbolToCurrencyPair converts a symbol (exchange specific market identifier) to a currency pair.
+func (b *Binance) SymbolToCurrencyPair(symbol string) (pair.CurrencyPair, error) {
+	b.marketsMtx.RLock()
+	defer b.marketsMtx.RUnlock()
+	if b.currencyPairs == nil {
+		return pair.CurrencyPair{}, ErrExchangeInfoNotLoaded
+	}
+	if p, exists := b.currencyPairs[pair.CurrencyItem(symbol)]; exists {
+		return p.Currency.FormatPair(
+			b.RequestCurrencyPairFormat.Delimiter, b.RequestCurrencyPairFormat.Uppercase), nil
+	}
+	return pair.CurrencyPair{}, fmt.Errorf("no currency pair found for '%s' symbol", symbol)
+}
+
+// FetchExchangeInfo fetches current exchange trading rules and symbol information.
+func (b *Binance) FetchExchangeInfo() (*ExchangeInfo, error) {
+	response := ExchangeInfo{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceExchangeInfoPath, nil, RequestSecurityNone, &response)
+	return &response, err
+}
+
+// FetchExchangeInfoForSymbols fetches current exchange trading rules and symbol information for
+// the given symbols only, which is much cheaper than fetching the info for all symbols.
+func (b *Binance) FetchExchangeInfoForSymbols(symbols []string) (*ExchangeInfo, error) {
+	v := url.Values{}
+	if len(symbols) == 1 {
+		v.Set("symbol", symbols[0])
+	} else if len(symbols) > 1 {
+		encodedSymbols, err := common.JSONEncode(symbols)
+		if err != nil {
+			return nil, err
+		}
+		v.Set("symbols", string(encodedSymbols))
+	}
+	response := ExchangeInfo{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceExchangeInfoPath, v, RequestSecurityNone, &response)
+	return &response, err
+}
+
+// LoadMarkets fetches the exchange info and caches the trading rules and symbol information
+// in it for use by the other methods.
+func (b *Binance) LoadMarkets() error {
+	exchangeInfo, err := b.FetchExchangeInfo()
+	if err != nil {
+		return err
+	}
+
+	currencyPairs := make(map[pair.CurrencyItem]*exchange.CurrencyPairInfo, len(exchangeInfo.Symbols))
+	symbolDetailsMap := make(map[pair.CurrencyItem]*symbolDetails, len(exchangeInfo.Symbols))
+	symbolInfo := make(map[string]*SymbolInfo, len(exchangeInfo.Symbols))
+	symbolFilters := make(map[string]*SymbolFilters, len(exchangeInfo.Symbols))
+	for i := range exchangeInfo.Symbols {
+		info := &exchangeInfo.Symbols[i]
+		symbolInfo[info.Symbol] = info
+		symbolFilters[info.Symbol] = newSymbolFilters(info)
+		currencyPair := pair.NewCurrencyPair(info.BaseAsset, info.QuoteAsset)
+		currencyPairs[pair.CurrencyItem(info.Symbol)] = &exchange.CurrencyPairInfo{Currency: currencyPair}
+		sd := symbolDetails{}
+		for _, filter := range info.Filters {
+			switch filter.Type {
+			case FilterTypePrice:
+				sd.PriceDecimalPlaces = filter.TickSize.Exponent() * -1
+			case FilterTypeLotSize:
+				sd.AmountDecimalPlaces = filter.StepSize.Exponent() * -1
+				sd.MinAmount, _ = filter.MinQty.Float64()
+			case FilterTypeMinNotional, FilterTypeNotional:
+				sd.MinTotal, _ = filter.MinNotional.Float64()
+			default:
+				// ignore
+			}
+		}
+		symbolDetailsMap[currencyPair.Display("/", false)] = &sd
+	}
+
+	b.marketsMtx.Lock()
+	defer b.marketsMtx.Unlock()
+	b.currencyPairs = currencyPairs
+	b.symbolDetailsMap = symbolDetailsMap
+	b.exchangeInfo = exchangeInfo
+	b.symbolInfo = symbolInfo
+	b.symbolFilters = symbolFilters
+
+	var orderLimits []RateLimit
+	for _, limit := range exchangeInfo.RateLimits {
+		if limit.RateLimitType == RateLimitTypeOrders && limit.Window() > 0 {
+			orderLimits = append(orderLimits, limit)
+		}
+	}
+	b.ordersMtx.Lock()
+	b.orderLimits = orderLimits
+	b.ordersMtx.Unlock()
+	return nil
+}
+
+// getSymbolInfo returns the cached information for the given symbol.
+func (b *Binance) getSymbolInfo(symbol string) (*SymbolInfo, error) {
+	b.marketsMtx.RLock()
+	defer b.marketsMtx.RUnlock()
+
+	if b.symbolInfo == nil {
+		return nil, ErrExchangeInfoNotLoaded
+	}
+	if info, exists := b.symbolInfo[symbol]; exists {
+		return info, nil
+	}
+	return nil, fmt.Errorf("no symbol info found for '%s' symbol", symbol)
+}
+
+// ValidateSymbol checks the given symbol exists in the cached exchange info and is currently
+// trading. If the symbol doesn't exist the returned error suggests the closest matching symbol
+// (if any), e.g. to catch typos in configured symbols.
+func (b *Binance) ValidateSymbol(symbol string) error {
+	b.marketsMtx.RLock()
+	defer b.marketsMtx.RUnlock()
+
+	if b.symbolInfo == nil {
+		return ErrExchangeInfoNotLoaded
+	}
+	if info, exists := b.symbolInfo[symbol]; exists {
+		if info.Status != SymbolStatusTrading {
+			return fmt.Errorf("symbol '%s' is not trading (status %s)", symbol, info.Status)
+		}
+		return nil
+	}
+	if suggestion := b.closestSymbol(symbol); suggestion != "" {
+		return fmt.Errorf("unknown symbol '%s', did you mean '%s'?", symbol, suggestion)
+	}
+	return fmt.Errorf("unknown symbol '%s'", symbol)
+}
+
+// closestSymbol returns the cached symbol with the smallest (case insensitive) edit distance to
+// the given symbol, or an empty string if none of the symbols are within half the length of the
+// given symbol (enough to catch a wrong base or quote asset). Must be called with the markets read
+// lock held.
+func (b *Binance) closestSymbol(symbol string) string {
+	maxDistance := len(symbol) / 2
+	upper := strings.ToUpper(symbol)
+	closest := ""
+	closestDistance := maxDistance + 1
+	for candidate := range b.symbolInfo {
+		d := editDistance(upper, candidate)
+		if d < closestDistance || (d == closestDistance && candidate < closest) {
+			closest, closestDistance = candidate, d
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between the given strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// MinNotional returns the min notional value (price * quantity) of an order for the given symbol,
+// or zero if the symbol doesn't have a NOTIONAL (or legacy MIN_NOTIONAL) filter.
+func (b *Binance) MinNotional(symbol string) (float64, error) {
+	filters, err := b.SymbolFilters(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return filters.MinNotional, nil
+}
+
+// SymbolFilters returns the price & quantity filters of the given symbol.
+func (b *Binance) SymbolFilters(symbol string) (*SymbolFilters, error) {
+	b.marketsMtx.RLock()
+	defer b.marketsMtx.RUnlock()
+
+	if b.symbolFilters == nil {
+		return nil, ErrExchangeInfoNotLoaded
+	}
+	filters, exists := b.symbolFilters[symbol]
+	if !exists {
+		return nil, fmt.Errorf("no symbol filters found for '%s' symbol", symbol)
+	}
+	filtersCopy := *filters
+	return &filtersCopy, nil
+}
+
+// ExchangeMaxNumOrders returns the max number of open orders the account can have across all
+// symbols, or zero if there's no such limit.
+func (b *Binance) ExchangeMaxNumOrders() (int, error) {
+	filter, err := b.getExchangeFilter(FilterTypeExchangeMaxNumOrders)
+	if err != nil || filter == nil {
+		return 0, err
+	}
+	return filter.MaxNumOrders, nil
+}
+
+// ExchangeMaxNumAlgoOrders returns the max number of open algo (STOP_LOSS, STOP_LOSS_LIMIT,
+// TAKE_PROFIT, TAKE_PROFIT_LIMIT) orders the account can have across all symbols, or zero if
+// there's no such limit.
+func (b *Binance) ExchangeMaxNumAlgoOrders() (int, error) {
+	filter, err := b.getExchangeFilter(FilterTypeExchangeMaxNumAlgoOrders)
+	if err != nil || filter == nil {
+		return 0, err
+	}
+	return filter.MaxNumAlgoOrders, nil
+}
+
+// getExchangeFilter returns the cached exchange filter of the given type, or nil if there's no
+// such filter.
+func (b *Binance) getExchangeFilter(filterType FilterType) (*ExchangeFilter, error) {
+	b.marketsMtx.RLock()
+	defer b.marketsMtx.RUnlock()
+
+	if b.exchangeInfo == nil {
+		return nil, ErrExchangeInfoNotLoaded
+	}
+	for i := range b.exchangeInfo.ExchangeFilters {
+		if b.exchangeInfo.ExchangeFilters[i].Type == filterType {
+			filter := b.exchangeInfo.ExchangeFilters[i]
+			return &filter, nil
+		}
+	}
+	return nil, nil
+}
+
+// AllowedOrderTypes returns the order types that can be used for the given symbol.
+func (b *Binance) AllowedOrderTypes(symbol string) ([]OrderType, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return append([]OrderType(nil), info.OrderTypes...), nil
+}
+
+// SupportsOrderType checks if the given order type can be used for the given symbol.
+func (b *Binance) SupportsOrderType(symbol string, t OrderType) (bool, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return false, err
+	}
+	for _, orderType := range info.OrderTypes {
+		if orderType == t {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SymbolPermissions returns the account permissions (e.g. SPOT or MARGIN) that allow trading the
+// given symbol.
+func (b *Binance) SymbolPermissions(symbol string) ([]string, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), info.Permissions...), nil
+}
+
+// SymbolSupportsSpot checks if the given symbol can be traded by spot accounts.
+func (b *Binance) SymbolSupportsSpot(symbol string) (bool, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range info.Permissions {
+		if permission == SymbolPermissionSpot {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FetchAccountInfo fetches current account information.
+// If this method gets rate limited it will return the account info obtained during the
+// last successful fetch, and an error matching exchange.WarningHTTPRequestRateLimited.
+func (b *Binance) FetchAccountInfo() (*AccountInfo, error) {
+	response := AccountInfo{}
+	err := b.SendRateLimitedHTTPRequest(20, http.MethodGet, binanceAccountPath, nil,
+		RequestSecuritySign, &response, b.lastAccountInfo)
+	if err != nil {
+		return &response, err
+	}
+	b.lastAccountInfo = response
+	return &response, nil
+}
+
+// fetchAccountInfo fetches current account information without the local throttling applied by
+// FetchAccountInfo, for callers that can't act on cached account information.
+func (b *Binance) fetchAccountInfo() (*AccountInfo, error) {
+	response := AccountInfo{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceAccountPath, nil, RequestSecuritySign, &response)
+	return &response, err
+}
+
+// GetAccountInfo returns a copy of the cached account information if it was fetched less than
+// maxAge ago, otherwise it fetches the current account information and caches it.
+func (b *Binance) GetAccountInfo(maxAge time.Duration) (*AccountInfo, error) {
+	// Hold the lock while fetching so concurrent callers don't all refresh the cache at once.
+	b.accountInfoMtx.Lock()
+	defer b.accountInfoMtx.Unlock()
+
+	if b.cachedAccountInfo != nil && time.Since(b.accountInfoFetchedAt) < maxAge {
+		return b.cachedAccountInfo.clone(), nil
+	}
+	info, err := b.FetchAccountInfo()
+	if err != nil {
+		return info, err
+	}
+	b.cachedAccountInfo = info.clone()
+	b.accountInfoFetchedAt = time.Now()
+	return info, nil
+}
+
+// FetchMarginAccount fetches current cross margin account information.
+func (b *Binance) FetchMarginAccount() (*MarginAccount, error) {
+	response := MarginAccount{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceMarginAccountPath, nil, RequestSecuritySign, &response)
+	return &response, err
+}
+
+// FetchMaxBorrowable fetches the max amount of the given asset that can currently be borrowed
+// on the cross margin account.
+func (b *Binance) FetchMaxBorrowable(asset string) (float64, error) {
+	v := url.Values{}
+	v.Set("asset", asset)
+	response := struct {
+		Amount float64 `json:"amount,string"`
+	}{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceMaxBorrowablePath, v, RequestSecuritySign, &response)
+	return response.Amount, err
+}
+
+// GetBNBBurnStatus fetches whether BNB is used to pay spot trading fees (spotBurn), and whether
+// BNB is used to pay margin loan interest (interestBurn).
+func (b *Binance) GetBNBBurnStatus() (spotBurn bool, interestBurn bool, err error) {
+	response := bnbBurnStatus{}
+	_, err = b.SendHTTPRequest(http.MethodGet, binanceBNBBurnPath, nil, RequestSecuritySign, &response)
+	return response.SpotBNBBurn, response.InterestBNBBurn, err
+}
+
+// FetchOrderRateLimitUsage fetches the current usage of the order rate limits of the account, i.e.
+// the number of orders placed within each of the limit's intervals. This is more accurate than the
+// local order counting done when placing orders, as it includes orders placed by other clients.
+func (b *Binance) FetchOrderRateLimitUsage() ([]RateLimitUsage, error) {
+	response := []RateLimitUsage{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceOrderRateLimitPath, nil, RequestSecuritySign, &response)
+	return response, err
+}
+
+// SetBNBBurnStatus sets whether BNB is used to pay spot trading fees (spotBurn), and whether BNB
+// is used to pay margin loan interest (interestBurn).
+func (b *Binance) SetBNBBurnStatus(spotBurn, interestBurn bool) error {
+	v := url.Values{}
+	v.Set("spotBNBBurn", strconv.FormatBool(spotBurn))
+	v.Set("interestBNBBurn", strconv.FormatBool(interestBurn))
+	response := bnbBurnStatus{}
+	_, err := b.SendHTTPRequest(http.MethodPost, binanceBNBBurnPath, v, RequestSecuritySign, &response)
+	if err != nil {
+		return err
+	}
+	if response.SpotBNBBurn != spotBurn || response.InterestBNBBurn != interestBurn {
+		return errors.New("BNB burn status wasn't updated")
+	}
+	return nil
+}
+
+// AvailableBuyingPower returns the amount of the given asset that can be spent on the cross margin
+// account, that's the net amount of the asset held on the account plus the max amount that can be
+// borrowed. Returns an error if margin trading isn't enabled on the account.
+func (b *Binance) AvailableBuyingPower(asset string) (float64, error) {
+	account, err := b.FetchMarginAccount()
+	if err != nil {
+		return 0, err
+	}
+	if !account.TradeEnabled || !account.BorrowEnabled {
+		return 0, errors.New("margin trading isn't enabled on the account")
+	}
+	borrowable, err := b.FetchMaxBorrowable(asset)
+	if err != nil {
+		return 0, err
+	}
+	buyingPower := decimal.NewFromFloat(borrowable)
+	for _, userAsset := range account.UserAssets {
+		if strings.EqualFold(userAsset.Asset, asset) {
+			buyingPower = buyingPower.Add(decimal.NewFromFloat(userAsset.NetAsset))
+			break
+		}
+	}
+	result, _ := buyingPower.Float64()
+	return result, nil
+}
+
+// FetchCombinedBalances fetches the spot & cross margin accounts concurrently and combines the
+// balances of each asset held on either account, the returned map is keyed by asset.
+func (b *Binance) FetchCombinedBalances() (map[string]CombinedBalance, error) {
+	var spot *AccountInfo
+	var margin *MarginAccount
+	var spotErr, marginErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		spot, spotErr = b.FetchAccountInfo()
+	}()
+	go func() {
+		defer wg.Done()
+		margin, marginErr = b.FetchMarginAccount()
+	}()
+	wg.Wait()
+	if spotErr != nil {
+		return nil, spotErr
+	}
+	if marginErr != nil {
+		return nil, marginErr
+	}
+
+	type totals struct {
+		free, locked, borrowed, net decimal.Decimal
+	}
+	assets := map[string]*totals{}
+	getTotals := func(asset string) *totals {
+		t, exists := assets[asset]
+		if !exists {
+			t = &totals{}
+			assets[asset] = t
+		}
+		return t
+	}
+	for _, balance := range spot.Balances {
+		free := decimal.NewFromFloat(balance.Free)
+		locked := decimal.NewFromFloat(balance.Locked)
+		t := getTotals(balance.Asset)
+		t.free = t.free.Add(free)
+		t.locked = t.locked.Add(locked)
+		t.net = t.net.Add(free).Add(locked)
+	}
+	for _, userAsset := range margin.UserAssets {
+		t := getTotals(userAsset.Asset)
+		t.free = t.free.Add(decimal.NewFromFloat(userAsset.Free))
+		t.locked = t.locked.Add(decimal.NewFromFloat(userAsset.Locked))
+		t.borrowed = t.borrowed.Add(decimal.NewFromFloat(userAsset.Borrowed))
+		t.net = t.net.Add(decimal.NewFromFloat(userAsset.NetAsset))
+	}
+
+	balances := make(map[string]CombinedBalance, len(assets))
+	for asset, t := range assets {
+		balance := CombinedBalance{Asset: asset}
+		balance.Free, _ = t.free.Float64()
+		balance.Locked, _ = t.locked.Float64()
+		balance.Borrowed, _ = t.borrowed.Float64()
+		balance.Net, _ = t.net.Float64()
+		balances[asset] = balance
+	}
+	return balances, nil
+}
+
+// FetchOpenOrders fetches all currently open orders for the given symbol.
+// If the symbol parameter is blank all open orders for the account will be returned,
+// this should generally be avoided as it's an expensive operation that can very quickly put
+// you over the request rate limit if this method is called multiple times per minute
+// (a request for all symbols has a weight of 40, compared to 3 for a single symbol, so it's
+// cheaper to fetch the open orders of up to 13 symbols one at a time).
+// If this method gets rate limited it will return the set of orders obtained during the
+// last successful fetch, and an error matching exchange.WarningHTTPRequestRateLimited.
+func (b *Binance) FetchOpenOrders(symbol string) ([]Order, error) {
+	v := url.Values{}
+	if symbol != "" {
+		v.Set("symbol", symbol)
+	}
+	lastOpenOrders := b.lastOpenOrders[symbol]
+	if lastOpenOrders == nil {
+		lastOpenOrders = []Order{}
+	}
+	response := []Order{}
+	err := b.SendRateLimitedHTTPRequest(10, http.MethodGet, binanceOpenOrdersPath, v,
+		RequestSecuritySign, &response, lastOpenOrders)
+	if err != nil {
+		return response, err
+	}
+	b.lastOpenOrders[symbol] = response
+	return response, nil
+}
+
+// fetchOpenOrders fetches all currently open orders for the given symbol (or for every symbol if
+// the symbol is blank) without the local throttling applied by FetchOpenOrders, for callers that
+// can't act on cached orders.
+func (b *Binance) fetchOpenOrders(symbol string) ([]Order, error) {
+	v := url.Values{}
+	if symbol != "" {
+		v.Set("symbol", symbol)
+	}
+	response := []Order{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceOpenOrdersPath, v, RequestSecuritySign, &response)
+	return response, err
+}
+
+// CountOpenOrders returns the number of currently open orders for the given symbol, e.g. to check
+// an order won't exceed the MAX_NUM_ORDERS filter before placing it. If the open orders are being
+// maintained by StartLiveAccount the live count is returned without making any requests, otherwise
+// the open orders are fetched via FetchOpenOrders (and the same rate limiting caveats apply).
+func (b *Binance) CountOpenOrders(symbol string) (int, error) {
+	if count, ok := b.liveOpenOrderCount(symbol); ok {
+		return count, nil
+	}
+	orders, err := b.FetchOpenOrders(symbol)
+	return len(orders), err
+}
+
+type PostOrderParams struct {
+	Symbol      string
+	Side        OrderSide
+	Type        OrderType
+	TimeInForce TimeInForce
+	Quantity    float64
+	// Amount of the quote asset to spend (or receive) on a MARKET order, can be set instead of
+	// the Quantity, see EstimateMarketBuyQuantity for the quantity this may result in. If the
+	// symbol info has been loaded the amount is floored to the quote asset precision when the order
+	// is placed.
+	QuoteOrderQty float64
+	Price         float64
+	// Set to a generated ID when the order is placed if left blank and the Binance client has a
+	// ClientOrderIDPrefix.
+	NewClientOrderID string
+	StopPrice        float64
+	IcebergQty       float64
+	// Trailing delta in basis points for trailing STOP_LOSS(_LIMIT) & TAKE_PROFIT(_LIMIT) orders, the
+	// order is triggered once the price moves by this delta against the best price seen since the
+	// order was placed (or since the stop price was reached, if a StopPrice is also set).
+	TrailingDelta int
+	// Overrides the default self-trade prevention mode of the symbol if set, it must be one of the
+	// modes allowed for the symbol.
+	SelfTradePreventionMode SelfTradePreventionMode
+	// Optional identifiers of the strategy that placed the order, for attribution in the order
+	// history. The strategy type must be at least 1000000 if set.
+	StrategyID   int
+	StrategyType int
+	// Set to true to submit the order to the test endpoint for validation,
+	// it won't be sent to the exchange matching engine.
+	ValidateOnly bool
+}
+
+// values returns the request parameters for the order.
+func (params *PostOrderParams) values() url.Values {
+	v := url.Values{}
+	v.Set("symbol", params.Symbol)
+	v.Set("side", string(params.Side))
+	v.Set("type", string(params.Type))
+	if params.TimeInForce != "" {
+		v.Set("timeInForce", string(params.TimeInForce))
+	}
+	if params.Quantity != 0 {
+		v.Set("quantity", strconv.FormatFloat(params.Quantity, 'f', -1, 64))
+	}
+	if params.QuoteOrderQty != 0 {
+		v.Set("quoteOrderQty", strconv.FormatFloat(params.QuoteOrderQty, 'f', -1, 64))
+	}
+	if params.Price != 0 {
+		v.Set("price", strconv.FormatFloat(params.Price, 'f', -1, 64))
+	}
+	if params.NewClientOrderID != "" {
+		v.Set("newClientOrderId", params.NewClientOrderID)
+	}
+	if params.StopPrice != 0 {
+		v.Set("stopPrice", strconv.FormatFloat(params.StopPrice, 'f', -1, 64))
+	}
+	if params.IcebergQty != 0 {
+		v.Set("icebergQty", strconv.FormatFloat(params.IcebergQty, 'f', -1, 64))
+	}
+	if params.TrailingDelta != 0 {
+		v.Set("trailingDelta", strconv.Itoa(params.TrailingDelta))
+	}
+	if params.SelfTradePreventionMode != "" {
+		v.Set("selfTradePreventionMode", string(params.SelfTradePreventionMode))
+	}
+	if params.StrategyID != 0 {
+		v.Set("strategyId", strconv.Itoa(params.StrategyID))
+	}
+	if params.StrategyType != 0 {
+		v.Set("strategyType", strconv.Itoa(params.StrategyType))
+	}
+	return v
+}
+
+// validateOrderParams checks the optional order parameters that the exchange would otherwise
+// reject, the checks against the symbol info are skipped if it hasn't been loaded.
+func (b *Binance) validateOrderParams(params *PostOrderParams) error {
+	if params.TrailingDelta != 0 {
+		switch params.Type {
+		case OrderTypeStopLoss, OrderTypeStopLossLimit, OrderTypeTakeProfit, OrderTypeTakeProfitLimit:
+		default:
+			return fmt.Errorf("trailing delta can't be used with %s orders", params.Type)
+		}
+		if params.TrailingDelta < 0 {
+			return fmt.Errorf("invalid trailing delta %d, must be positive", params.TrailingDelta)
+		}
+	}
+	if params.StrategyType != 0 && params.StrategyType < minStrategyType {
+		return fmt.Errorf("invalid strategy type %d, must be at least %d", params.StrategyType, minStrategyType)
+	}
+	if params.QuoteOrderQty != 0 {
+		if allowed, err := b.QuoteOrderQtyMarketAllowed(params.Symbol); err == nil && !allowed {
+			return fmt.Errorf("quote order quantity is not allowed for '%s' symbol", params.Symbol)
+		}
+	}
+	if mode := params.SelfTradePreventionMode; mode != "" {
+		if !containsSelfTradePreventionMode(AllSelfTradePreventionModes, mode) {
+			return fmt.Errorf("invalid self-trade prevention mode '%s'", mode)
+		}
+		if info, err := b.getSymbolInfo(params.Symbol); err == nil &&
+			len(info.AllowedSelfTradePreventionModes) > 0 &&
+			!containsSelfTradePreventionMode(info.AllowedSelfTradePreventionModes, mode) {
+			return fmt.Errorf("self-trade prevention mode '%s' is not allowed for '%s' symbol",
+				mode, params.Symbol)
+		}
+	}
+	return nil
+}
+
+func containsSelfTradePreventionMode(modes []SelfTradePreventionMode, mode SelfTradePreventionMode) bool {
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOrderRespType(respTypes []OrderRespType, respType OrderRespType) bool {
+	for _, t := range respTypes {
+		if t == respType {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Binance) PostOrderAck(params *PostOrderParams) (*PostOrderAckResponse, error) {
+	response := PostOrderAckResponse{}
+	err := b.postOrder(params, OrderRespTypeAck, &response)
+	return &response, err
+}
+
+// PostOrderFull places an order and returns the full order details, including the fills if the
+// order was filled immediately.
+func (b *Binance) PostOrderFull(params *PostOrderParams) (*PostOrderFullResponse, error) {
+	response := PostOrderFullResponse{}
+	err := b.postOrder(params, OrderRespTypeFull, &response)
+	return &response, err
+}
+
+// PostOrder places an order and returns the order details provided by DefaultOrderRespType (or
+// by the ACK response type if it isn't set), use the HasResult and HasFills methods of the
+// response to find out which details are populated.
+func (b *Binance) PostOrder(params *PostOrderParams) (*PostOrderResponse, error) {
+	respType := b.DefaultOrderRespType
+	if respType == "" {
+		respType = OrderRespTypeAck
+	}
+	response := PostOrderResponse{RespType: respType}
+	if !containsOrderRespType(AllOrderRespTypes, respType) {
+		return &response, fmt.Errorf("invalid order response type '%s'", respType)
+	}
+	err := b.postOrder(params, respType, &response)
+	return &response, err
+}
+
+// postOrder places an order requesting the given response type, and decodes the response into the
+// given result.
+func (b *Binance) postOrder(params *PostOrderParams, respType OrderRespType, result interface{}) error {
+	if err := b.validateOrderParams(params); err != nil {
+		return err
+	}
+	if !params.ValidateOnly {
+		if err := b.reserveOrders(1); err != nil {
+			return err
+		}
+	}
+	b.assignClientOrderID(params)
+	b.roundQuoteOrderQty(params)
+	v := params.values()
+	v.Set("newOrderRespType", string(respType))
+
+	path := binanceOrderPath
+	if params.ValidateOnly {
+		path = binanceOrderTestPath
+	}
+	_, err := b.SendHTTPRequest(http.MethodPost, path, v, RequestSecuritySign, result)
+	return err
+}
+
+// PostOrderResult is the result of placing one of the orders passed to PostOrders, either Order or
+// Err is set.
+type PostOrderResult struct {
+	Order *PostOrderAckResponse
+	Err   error
+}
+
+// PostOrders places the given orders one at a time (subject to the same order rate limits as
+// PostOrderAck), and returns the result of placing each order at the same index as its params.
+// A failure to place one of the orders doesn't prevent the remaining orders from being placed,
+// the returned error is non-nil if any of the orders failed, and the per-order results report
+// which ones did.
+func (b *Binance) PostOrders(params []*PostOrderParams) ([]PostOrderResult, error) {
+	results := make([]PostOrderResult, len(params))
+	failed := 0
+	for i, p := range params {
+		if p == nil {
+			results[i].Err = errors.New("order params must not be nil")
+		} else if order, err := b.PostOrderAck(p); err != nil {
+			results[i].Err = err
+		} else {
+			results[i].Order = order
+		}
+		if results[i].Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("failed to place %d of %d orders", failed, len(params))
+	}
+	return results, nil
+}
+
+// GenerateClientOrderID returns a new client order ID made up of ClientOrderIDPrefix followed by a
+// suffix that's unique across calls and restarts. The prefix is truncated if necessary to keep the
+// ID within the 36 character limit imposed by Binance.
+func (b *Binance) GenerateClientOrderID() string {
+	seq := atomic.AddUint32(&b.clientOrderIDSeq, 1)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(uint64(seq), 36)
+	prefix := b.ClientOrderIDPrefix
+	if len(prefix)+len(suffix) > maxClientOrderIDLength {
+		prefix = prefix[:maxClientOrderIDLength-len(suffix)]
+	}
+	return prefix + suffix
+}
+
+// QuoteOrderQtyMarketAllowed checks if MARKET orders for the given symbol can specify the amount
+// of the quote asset to spend (or receive) rather than the quantity.
+func (b *Binance) QuoteOrderQtyMarketAllowed(symbol string) (bool, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return false, err
+	}
+	return info.QuoteOrderQtyMarketAllowed, nil
+}
+
+// RoundQuoteOrderQty floors the given amount of the quote asset of the given symbol to the quote
+// asset precision, so it can be used as the quote order quantity of a MARKET order.
+func (b *Binance) RoundQuoteOrderQty(symbol string, quoteQty float64) (float64, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return 0, err
+	}
+	result, _ := decimal.NewFromFloat(quoteQty).Truncate(int32(info.QuoteAssetPrecision)).Float64()
+	return result, nil
+}
+
+// roundQuoteOrderQty floors the quote order quantity of an order to the quote asset precision,
+// unless the symbol info hasn't been loaded.
+func (b *Binance) roundQuoteOrderQty(params *PostOrderParams) {
+	if params.QuoteOrderQty == 0 {
+		return
+	}
+	if rounded, err := b.RoundQuoteOrderQty(params.Symbol, params.QuoteOrderQty); err == nil {
+		params.QuoteOrderQty = rounded
+	}
+}
+
+// assignClientOrderID sets the client order ID of an order that doesn't have one to a generated
+// ID if ClientOrderIDPrefix is set.
+func (b *Binance) assignClientOrderID(params *PostOrderParams) {
+	if params.NewClientOrderID == "" && b.ClientOrderIDPrefix != "" {
+		params.NewClientOrderID = b.GenerateClientOrderID()
+	}
+}
+
+// PlaceOrderIdempotent places an order unless an order with the same client order ID already
+// exists on the exchange, in which case the existing order is returned instead, this makes it
+// safe to retry placing an order after a crash or a network error.
+// NOTE: params.NewClientOrderID must be set.
+func (b *Binance) PlaceOrderIdempotent(params *PostOrderParams) (*PostOrderAckResponse, error) {
+	if params.NewClientOrderID == "" {
+		return nil, errors.New("NewClientOrderID must be set to place an order idempotently")
+	}
+	v := url.Values{}
+	v.Set("symbol", params.Symbol)
+	v.Set("origClientOrderId", params.NewClientOrderID)
+	order := Order{}
+	code, err := b.SendHTTPRequest(http.MethodGet, binanceOrderPath, v, RequestSecuritySign, &order)
+	if err == nil {
+		return &PostOrderAckResponse{
+			Symbol:        order.Symbol,
+			OrderID:       order.OrderID,
+			ClientOrderID: order.ClientOrderID,
+			TransactTime:  order.Time,
+		}, nil
+	}
+	if BinanceErrCode(code) != NoSuchOrderErrCode {
+		return nil, err
+	}
+	return b.PostOrderAck(params)
+}
+
+// EstimateMarketBuyQuantity estimates the quantity a MARKET buy order spending quoteQty of the
+// quote asset will be filled for at the best ask price in the given book, the quantity is
+// floored to the LOT_SIZE step size of the symbol.
+func (b *Binance) EstimateMarketBuyQuantity(symbol string, quoteQty float64, book *orderbook.Base) (float64, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return 0, err
+	}
+	if len(book.Asks) == 0 || book.Asks[0].Price <= 0 {
+		return 0, errors.New(orderbook.ErrOrderbookSideEmpty)
+	}
+	qty := decimal.NewFromFloat(quoteQty).Div(decimal.NewFromFloat(book.Asks[0].Price))
+	for _, filter := range info.Filters {
+		if filter.Type == FilterTypeLotSize && filter.StepSize.Sign() > 0 {
+			qty = qty.Div(filter.StepSize).Floor().Mul(filter.StepSize)
+		}
+	}
+	result, _ := qty.Float64()
+	return result, nil
+}
+
+// MaxOrderQuantity returns the max quantity of an order at the given limit price that's covered by
+// the free balance in the given account (the quote asset balance for a buy, the base asset
+// balance for a sell), floored to the LOT_SIZE step size and capped at the LOT_SIZE max quantity.
+// Returns an error if that quantity doesn't meet the LOT_SIZE min quantity or MIN_NOTIONAL, or if
+// the account is nil.
+func (b *Binance) MaxOrderQuantity(symbol string, side OrderSide, limitPrice float64,
+	account *AccountInfo) (float64, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return 0, err
+	}
+	if limitPrice <= 0 {
+		return 0, errors.New("limit price must be greater than zero")
+	}
+	if account == nil {
+		return 0, errors.New("account info must not be nil")
+	}
+	price := decimal.NewFromFloat(limitPrice)
+	var qty decimal.Decimal
+	switch side {
+	case OrderSideBuy:
+		qty = decimal.NewFromFloat(account.Free(info.QuoteAsset)).Div(price)
+	case OrderSideSell:
+		qty = decimal.NewFromFloat(account.Free(info.BaseAsset))
+	default:
+		return 0, fmt.Errorf("invalid order side '%s'", side)
+	}
+
+	for _, filter := range info.Filters {
+		if filter.Type != FilterTypeLotSize {
+			continue
+		}
+		if filter.MaxQty.Sign() > 0 && qty.GreaterThan(filter.MaxQty) {
+			qty = filter.MaxQty
+		}
+		if filter.StepSize.Sign() > 0 {
+			qty = qty.Div(filter.StepSize).Floor().Mul(filter.StepSize)
+		}
+	}
+	// Check the adjusted quantity against the minimums.
+	for _, filter := range info.Filters {
+		switch filter.Type {
+		case FilterTypeLotSize:
+			if qty.LessThan(filter.MinQty) || !qty.IsPositive() {
+				return 0, exchange.ErrInsufficentFundsForOrder()
+			}
+		case FilterTypeMinNotional, FilterTypeNotional:
+			if qty.Mul(price).LessThan(filter.MinNotional) {
+				return 0, exchange.ErrInsufficentFundsForOrder()
+			}
+		}
+	}
+	result, _ := qty.Float64()
+	return result, nil
+}
+
+// SplitOrder splits the given total quantity of an order at the given price into quantities for
+// the given number of child orders (e.g. for TWAP execution). The total is floored to the LOT_SIZE
+// step size and split as evenly as possible, each child quantity is a multiple of the step size
+// and the remainder is spread over the first child orders one step at a time. Returns an error if
+// the child quantities don't meet the LOT_SIZE or MIN_NOTIONAL filters.
+func (b *Binance) SplitOrder(symbol string, totalQty float64, slices int, price float64) ([]float64, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if slices <= 0 {
+		return nil, errors.New("number of slices must be greater than zero")
+	}
+	if totalQty <= 0 || price <= 0 {
+		return nil, errors.New("total quantity & price must be greater than zero")
+	}
+
+	var lotSize, minNotional *SymbolInfoFilter
+	for i := range info.Filters {
+		switch info.Filters[i].Type {
+		case FilterTypeLotSize:
+			lotSize = &info.Filters[i]
+		case FilterTypeMinNotional, FilterTypeNotional:
+			minNotional = &info.Filters[i]
+		}
+	}
+	total := decimal.NewFromFloat(totalQty)
+	n := decimal.New(int64(slices), 0)
+	quantities := make([]decimal.Decimal, slices)
+	if lotSize != nil && lotSize.StepSize.Sign() > 0 {
+		steps := total.Div(lotSize.StepSize).Floor()
+		stepsPerSlice := steps.Div(n).Floor()
+		remainder := steps.Sub(stepsPerSlice.Mul(n)).IntPart()
+		for i := range quantities {
+			sliceSteps := stepsPerSlice
+			if int64(i) < remainder {
+				sliceSteps = sliceSteps.Add(decimal.New(1, 0))
+			}
+			quantities[i] = sliceSteps.Mul(lotSize.StepSize)
+		}
+	} else {
+		sliceQty := total.Div(n)
+		for i := range quantities {
+			quantities[i] = sliceQty
+		}
+	}
+
+	// The last child order has the smallest quantity, and the first the largest.
+	smallest, largest := quantities[slices-1], quantities[0]
+	if !smallest.IsPositive() {
+		return nil, fmt.Errorf("total quantity %v is too small to split into %d slices", totalQty, slices)
+	}
+	if lotSize != nil {
+		if smallest.LessThan(lotSize.MinQty) {
+			return nil, fmt.Errorf("slice quantity %s is below the min quantity %s", smallest, lotSize.MinQty)
+		}
+		if lotSize.MaxQty.Sign() > 0 && largest.GreaterThan(lotSize.MaxQty) {
+			return nil, fmt.Errorf("slice quantity %s is above the max quantity %s", largest, lotSize.MaxQty)
+		}
+	}
+	if minNotional != nil {
+		if notional := smallest.Mul(decimal.NewFromFloat(price)); notional.LessThan(minNotional.MinNotional) {
+			return nil, fmt.Errorf("slice notional %s is below the min notional %s", notional,
+				minNotional.MinNotional)
+		}
+	}
+	result := make([]float64, slices)
+	for i, qty := range quantities {
+		result[i], _ = qty.Float64()
+	}
+	return result, nil
+}
+
+// FormatQuote formats the given value of the quote asset of the given symbol for display, with as
+// many decimal places as the quote asset precision of the symbol (the value is rounded if needed).
+// Unlike strconv/fmt formatting of floats the result never uses scientific notation, and always has
+// the same number of decimal places.
+func (b *Binance) FormatQuote(symbol string, value float64) (string, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return "", err
+	}
+	return decimal.NewFromFloat(value).StringFixed(int32(info.QuoteAssetPrecision)), nil
+}
+
+// FormatBase formats the given quantity of the base asset of the given symbol for display, with as
+// many decimal places as the base asset precision of the symbol (see FormatQuote).
+func (b *Binance) FormatBase(symbol string, qty float64) (string, error) {
+	info, err := b.getSymbolInfo(symbol)
+	if err != nil {
+		return "", err
+	}
+	return decimal.NewFromFloat(qty).StringFixed(int32(info.BaseAssetPrecision)), nil
+}
+
+// FetchOrder fetches an order from the exchange, either orderID or clientOrderID must be provided.
+func (b *Binance) FetchOrder(symbol string, orderID int64, clientOrderID string) (*Order, error) {
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	if orderID != 0 {
+		v.Set("orderId", strconv.FormatInt(orderID, 10))
+	}
+	if clientOrderID != "" {
+		v.Set("origClientOrderId", clientOrderID)
+	}
+	response := Order{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceOrderPath, v, RequestSecuritySign, &response)
+	return &response, err
+}
+
+// WaitForOrder blocks until the given order reaches a terminal state (filled, canceled, rejected,
+// or expired) and returns the final order, or returns the context error if the context is done
+// first. The order is polled via FetchOrder at the given interval. While StartLiveAccount is
+// running and the execution reports received via the user data stream show the order is still
+// open the order is only polled every 10 intervals, so a missed execution report only delays the
+// result rather than blocking it until the context is done.
+func (b *Binance) WaitForOrder(ctx context.Context, symbol string, orderID int64, poll time.Duration) (*Order, error) {
+	if poll <= 0 {
+		return nil, errors.New("poll interval must be greater than zero")
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for tick := 0; ; tick++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if open, live := b.liveOrderOpen(symbol, orderID); !live || !open || tick%waitForOrderLivePollTicks == 0 {
+			order, err := b.FetchOrder(symbol, orderID, "")
+			if err != nil {
+				return nil, err
+			}
+			if order.IsTerminal() {
+				return order, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// FetchAllOrders fetches all the account orders for the given symbol, active, canceled, or filled.
+// If orderID is non-zero orders with IDs greater than or equal to it are returned, otherwise the
+// most recent orders are returned. The start & end times are optional (zero values are ignored).
+// The limit parameter can be 0 to use the default value (currently 500), or at most 1000.
+func (b *Binance) FetchAllOrders(symbol string, orderID int64, startTime, endTime time.Time,
+	limit int) ([]Order, error) {
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	if orderID != 0 {
+		v.Set("orderId", strconv.FormatInt(orderID, 10))
+	}
+	if !startTime.IsZero() {
+		v.Set("startTime", strconv.FormatInt(startTime.UnixNano()/int64(time.Millisecond), 10))
+	}
+	if !endTime.IsZero() {
+		v.Set("endTime", strconv.FormatInt(endTime.UnixNano()/int64(time.Millisecond), 10))
+	}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	response := []Order{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceAllOrdersPath, v, RequestSecuritySign, &response)
+	return response, err
+}
+
+// FetchMostRecentOrder fetches the most recently placed account order for the given symbol,
+// whether it's active, canceled, or filled. Returns ErrNoOrders if there are no orders.
+func (b *Binance) FetchMostRecentOrder(symbol string) (*Order, error) {
+	orders, err := b.FetchAllOrders(symbol, 0, time.Time{}, time.Time{}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, ErrNoOrders
+	}
+	// The orders are sorted by ascending order ID, so the most recent one is last.
+	return &orders[len(orders)-1], nil
+}
+
+// FetchAllOrdersPaged fetches all the account orders for the given symbol that were created
+// within the given time range, sorted by creation time. Orders are fetched a page at a time,
+// waiting between pages if necessary to stay within the request weight limit. The start time must
+// be provided, a zero end time is treated as the current time.
+func (b *Binance) FetchAllOrdersPaged(symbol string, start, end time.Time) ([]Order, error) {
+	const pageSize = 1000
+	if start.IsZero() {
+		return nil, errors.New("start time must be provided")
+	}
+	if end.IsZero() {
+		end = b.now()
+	}
+	if end.Before(start) {
+		return nil, errors.New("end time must not be before the start time")
+	}
+	startMsecs := start.UnixNano() / int64(time.Millisecond)
+	endMsecs := end.UnixNano() / int64(time.Millisecond)
+	seen := map[int64]bool{}
+	result := []Order{}
+	var nextOrderID int64
+	for {
+		b.waitForRequestWeight(EndpointWeight(http.MethodGet, binanceAllOrdersPath, nil))
+		var page []Order
+		var err error
+		if nextOrderID == 0 {
+			page, err = b.FetchAllOrders(symbol, 0, start, time.Time{}, pageSize)
+		} else {
+			page, err = b.FetchAllOrders(symbol, nextOrderID, time.Time{}, time.Time{}, pageSize)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pastEnd := false
+		for _, order := range page {
+			if order.Time > endMsecs {
+				pastEnd = true
+				continue
+			}
+			if order.Time >= startMsecs && !seen[order.OrderID] {
+				seen[order.OrderID] = true
+				result = append(result, order)
+			}
+			if order.OrderID >= nextOrderID {
+				nextOrderID = order.OrderID + 1
+			}
+		}
+		if pastEnd || len(page) < pageSize {
+			break
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Time == result[j].Time {
+			return result[i].OrderID < result[j].OrderID
+		}
+		return result[i].Time < result[j].Time
+	})
+	return result, nil
+}
+
+// FetchOrdersByStatus fetches the account orders for the given symbol that were created within the
+// given time range and have the given status, sorted by creation time. Binance can't filter orders
+// by status, so all the orders in the time range are fetched (see FetchAllOrdersPaged) and then
+// filtered.
+func (b *Binance) FetchOrdersByStatus(symbol string, status OrderStatus, start, end time.Time) ([]Order, error) {
+	orders, err := b.FetchAllOrdersPaged(symbol, start, end)
+	if err != nil {
+		return nil, err
+	}
+	result := []Order{}
+	for _, order := range orders {
+		if order.Status == status {
+			result = append(result, order)
+		}
+	}
+	return result, nil
+}
+
+// FetchMyTrades fetches the account trades for the given symbol. If orderID is non-zero only the
+// trades that filled that order are returned. If fromID is non-zero trades with IDs greater than
+// or equal to it are returned, otherwise the most recent trades are returned.
+// The limit parameter can be 0 to use the default value (currently 500), or at most 1000.
+func (b *Binance) FetchMyTrades(symbol string, orderID, fromID int64, limit int) ([]AccountTrade, error) {
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	if orderID != 0 {
+		v.Set("orderId", strconv.FormatInt(orderID, 10))
+	}
+	if fromID != 0 {
+		v.Set("fromId", strconv.FormatInt(fromID, 10))
+	}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	response := []AccountTrade{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceMyTradesPath, v, RequestSecuritySign, &response)
+	return response, err
+}
+
+// FetchPreventedMatches fetches the matches of the account orders for the given symbol that were
+// prevented by self-trade prevention (see PostOrderParams.SelfTradePreventionMode), either the
+// prevented match with the given ID, or the prevented matches of the given order. When querying
+// by order, if fromPreventedMatchID is non-zero prevented matches with IDs greater than or equal to
+// it are returned. The limit parameter can be 0 to use the default value (currently 500), or at
+// most 1000.
+func (b *Binance) FetchPreventedMatches(symbol string, preventedMatchID, orderID, fromPreventedMatchID int64,
+	limit int) ([]PreventedMatch, error) {
+	if symbol == "" {
+		return nil, errors.New("symbol must be provided")
+	}
+	if (preventedMatchID == 0) == (orderID == 0) {
+		return nil, errors.New("either the prevented match ID or the order ID must be provided")
+	}
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	if preventedMatchID != 0 {
+		v.Set("preventedMatchId", strconv.FormatInt(preventedMatchID, 10))
+	} else {
+		v.Set("orderId", strconv.FormatInt(orderID, 10))
+		if fromPreventedMatchID != 0 {
+			v.Set("fromPreventedMatchId", strconv.FormatInt(fromPreventedMatchID, 10))
+		}
+		if limit > 0 {
+			v.Set("limit", strconv.Itoa(limit))
+		}
+	}
+	response := []PreventedMatch{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binancePreventedMatchesPath, v, RequestSecuritySign, &response)
+	return response, err
+}
+
+// FetchMyAllocations fetches the allocations of the account orders for the given symbol that were
+// routed by Smart Order Routing (SOR), these aren't included in the trades returned by
+// FetchMyTrades. If orderID is non-zero only the allocations of that order are returned, and if
+// fromAllocationID is non-zero allocations with IDs greater than or equal to it are returned. The
+// start & end times are optional (zero values are ignored). The limit parameter can be 0 to use
+// the default value (currently 500), or at most 1000.
+func (b *Binance) FetchMyAllocations(symbol string, startTime, endTime time.Time, fromAllocationID, orderID,
+	limit int64) ([]Allocation, error) {
+	if symbol == "" {
+		return nil, errors.New("symbol must be provided")
+	}
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	if !startTime.IsZero() {
+		v.Set("startTime", strconv.FormatInt(startTime.UnixNano()/int64(time.Millisecond), 10))
+	}
+	if !endTime.IsZero() {
+		v.Set("endTime", strconv.FormatInt(endTime.UnixNano()/int64(time.Millisecond), 10))
+	}
+	if fromAllocationID != 0 {
+		v.Set("fromAllocationId", strconv.FormatInt(fromAllocationID, 10))
+	}
+	if orderID != 0 {
+		v.Set("orderId", strconv.FormatInt(orderID, 10))
+	}
+	if limit > 0 {
+		v.Set("limit", strconv.FormatInt(limit, 10))
+	}
+	response := []Allocation{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceMyAllocationsPath, v, RequestSecuritySign, &response)
+	return response, err
+}
+
+// FetchRecentTrades fetches the most recent public trades for the given symbol.
+// The limit parameter can be 0 to use the default value (currently 500), or at most 1000.
+func (b *Binance) FetchRecentTrades(symbol string, limit int) ([]Trade, error) {
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	response := []Trade{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceRecentTradesPath, v, RequestSecurityNone, &response)
+	return response, err
+}
+
+// AnnotateFills cross-references the fills in the given order response with the account trades
+// that filled the order to determine whether the order was the maker in each fill.
+func (b *Binance) AnnotateFills(r *PostOrderFullResponse) ([]AnnotatedFill, error) {
+	if len(r.Fills) == 0 {
+		return []AnnotatedFill{}, nil
+	}
+	trades, err := b.FetchMyTrades(r.Symbol, r.OrderID, 0, 1000)
+	if err != nil {
+		return nil, err
+	}
+	isMaker := make(map[int64]bool, len(trades))
+	for _, trade := range trades {
+		isMaker[trade.ID] = trade.IsMaker
+	}
+	fills := make([]AnnotatedFill, len(r.Fills))
+	for i, fill := range r.Fills {
+		maker, found := isMaker[fill.TradeID]
+		if !found {
+			return nil, fmt.Errorf("no trade found for fill %d of order %d", fill.TradeID, r.OrderID)
+		}
+		fills[i] = AnnotatedFill{OrderFill: fill, IsMaker: maker}
+	}
+	return fills, nil
+}
+
+// DeleteOrder cancels an active order on the exchange, either orderID or clientOrderID must be provided.
+// Cancelling an order is idempotent, so the request is retried if it fails due to a network error,
+// and an order that doesn't exist (or has already been cancelled) is treated as cancelled.
+func (b *Binance) DeleteOrder(symbol string, orderID int64, clientOrderID string) error {
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	if orderID != 0 {
+		v.Set("orderId", strconv.FormatInt(orderID, 10))
+	}
+	if clientOrderID != "" {
+		v.Set("origClientOrderId", clientOrderID)
+	}
+	bo := b.requestBackoff()
+	for {
+		response := DeleteOrderResponse{}
+		code, err := b.SendHTTPRequest(http.MethodDelete, binanceOrderPath, v, RequestSecuritySign, &response)
+		if BinanceErrCode(code) == CancelRejectedErrCode {
+			return nil
+		}
+		if _, isNetErr := err.(net.Error); !isNetErr || !bo.wait(nil) {
+			return err
+		}
+		b.logf("%s retrying cancellation of %s order after error: %s\n", b.GetName(), symbol, err)
+	}
+}
+
+// DeleteAllOpenOrders cancels all active orders (including OCO orders) for the given symbol.
+func (b *Binance) DeleteAllOpenOrders(symbol string) ([]DeleteOrderResponse, error) {
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	response := []DeleteOrderResponse{}
+	_, err := b.SendHTTPRequest(http.MethodDelete, binanceOpenOrdersPath, v, RequestSecuritySign, &response)
+	return response, err
+}
+
+// DeleteAllOpenOrdersEverywhere cancels all active orders for every symbol that has any. Orders
+// are cancelled for as many symbols as possible even if cancelling the orders of some symbols
+// fails, the returned error lists the symbols whose orders may not have been cancelled. The open
+// orders are always fetched from the exchange, never from the FetchOpenOrders cache.
+func (b *Binance) DeleteAllOpenOrdersEverywhere() ([]DeleteOrderResponse, error) {
+	openOrders, err := b.fetchOpenOrders("")
+	if err != nil {
+		return nil, err
+	}
+	var symbols []string
+	seen := map[string]bool{}
+	for _, order := range openOrders {
+		if !seen[order.Symbol] {
+			seen[order.Symbol] = true
+			symbols = append(symbols, order.Symbol)
+		}
+	}
+
+	result := []DeleteOrderResponse{}
+	var failed []string
+	for _, symbol := range symbols {
+		b.waitForRequestWeight(EndpointWeight(http.MethodDelete, binanceOpenOrdersPath, nil))
+		cancelled, err := b.DeleteAllOpenOrders(symbol)
+		if err != nil {
+			b.logf("%s failed to cancel %s orders: %s\n", b.GetName(), symbol, err)
+			failed = append(failed, symbol)
+			continue
+		}
+		result = append(result, cancelled...)
+	}
+	if len(failed) > 0 {
+		return result, fmt.Errorf("failed to cancel orders for symbols: %s", strings.Join(failed, ", "))
+	}
+	return result, nil
 }
 
-// CurrencyPairToSymbol converts a currency pair to a symbol (exchange specific market identifier).
-func (b *Binance) CurrencyPairToSymbol(p pair.CurrencyPair) string {
-	return p.
-		Display(b.RequestCurrencyPairFormat.Delimiter, b.RequestCurrencyPairFormat.Uppercase).
-		String()
+// FetchOpenOCOOrders fetches all currently open OCO order lists.
+func (b *Binance) FetchOpenOCOOrders() ([]OCOOrder, error) {
+	response := []OCOOrder{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceOpenOrderListPath, nil, RequestSecuritySign, &response)
+	return response, err
 }
 
-// SymbolToCurrencyPair converts a symbol (exchange specific market identifier) to a currency pair.
-func (b *Binance) SymbolToCurrencyPair(symbol string) (pair.CurrencyPair, error) {
-	if p, exists := b.currencyPairs[pair.CurrencyItem(symbol)]; exists {
-		return p.Currency.FormatPair(
-			b.RequestCurrencyPairFormat.Delimiter, b.RequestCurrencyPairFormat.Uppercase), nil
+// FetchOCOOrder fetches an OCO order list from the exchange, either orderListID or
+// listClientOrderID must be provided.
+func (b *Binance) FetchOCOOrder(orderListID int64, listClientOrderID string) (*OCOOrder, error) {
+	v := url.Values{}
+	if orderListID != 0 {
+		v.Set("orderListId", strconv.FormatInt(orderListID, 10))
 	}
-	return pair.CurrencyPair{}, fmt.Errorf("no currency pair found for '%s' symbol", symbol)
+	if listClientOrderID != "" {
+		v.Set("origClientOrderId", listClientOrderID)
+	}
+	response := OCOOrder{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceOrderListPath, v, RequestSecuritySign, &response)
+	return &response, err
 }
 
-// FetchExchangeInfo fetches current exchange trading rules and symbol information.
-func (b *Binance) FetchExchangeInfo() (*ExchangeInfo, error) {
-	response := ExchangeInfo{}
-	err := common.SendHTTPGetRequest(binanceBaseURL+binanceExchangeInfoPath, true, b.Verbose, &response)
-	return &response, err
+// CreateListenKey starts a new user data stream and returns the listen key for it, the stream
+// will be closed by the exchange after 60 minutes unless KeepAliveListenKey is called.
+func (b *Binance) CreateListenKey() (string, error) {
+	response := struct {
+		ListenKey string `json:"listenKey"`
+	}{}
+	_, err := b.SendHTTPRequest(http.MethodPost, binanceUserDataStreamPath, nil, RequestSecurityAuth, &response)
+	return response.ListenKey, err
 }
 
-// FetchAccountInfo fetches current account information.
-// If this method gets rate limited it will return the account info obtained during the
-// last successful fetch, and an error matching exchange.WarningHTTPRequestRateLimited.
-func (b *Binance) FetchAccountInfo() (*AccountInfo, error) {
-	response := AccountInfo{}
-	err := b.SendRateLimitedHTTPRequest(20, http.MethodGet, binanceAccountPath, nil,
-		RequestSecuritySign, &response, b.lastAccountInfo)
-	if err != nil {
-		return &response, err
-	}
-	b.lastAccountInfo = response
-	return &response, nil
+// KeepAliveListenKey extends the validity of the given listen key by 60 minutes.
+func (b *Binance) KeepAliveListenKey(listenKey string) error {
+	v := url.Values{}
+	v.Set("listenKey", listenKey)
+	_, err := b.SendHTTPRequest(http.MethodPut, binanceUserDataStreamPath, v, RequestSecurityAuth, &struct{}{})
+	return err
 }
 
-// FetchOpenOrders fetches all currently open orders.
-// If the symbol parameter is blank all open orders for the account will be returned,
-// this should generally be avoided as it's an expensive operation that can very quickly put
-// you over the request rate limit if this method is called multiple times per minute.
-// If this method gets rate limited it will return the set of orders obtained during the
-// last successful fetch, and an error matching exchange.WarningHTTPRequestRateLimited.
-func (b *Binance) FetchOpenOrders(symbol string) ([]Order, error) {
+// CloseListenKey closes the user data stream associated with the given listen key.
+func (b *Binance) CloseListenKey(listenKey string) error {
 	v := url.Values{}
-	if symbol != "" {
-		v.Set("symbol", symbol)
+	v.Set("listenKey", listenKey)
+	_, err := b.SendHTTPRequest(http.MethodDelete, binanceUserDataStreamPath, v, RequestSecurityAuth, &struct{}{})
+	return err
+}
+
+// FetchAllCoinsInfo fetches information about all the coins available for deposit & withdrawal,
+// including the networks each coin can be deposited to or withdrawn from.
+func (b *Binance) FetchAllCoinsInfo() ([]CoinInfo, error) {
+	response := []CoinInfo{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceAllCoinsInfoPath, nil, RequestSecuritySign, &response)
+	return response, err
+}
+
+// FetchAssetDividends fetches the asset distribution (e.g. airdrop & dividend) records of the
+// given asset, or of all assets if the asset is blank. The start & end times are optional (zero
+// values are ignored), but if both are set they must be no more than 90 days apart.
+// The limit parameter can be 0 to use the default value (currently 20), or at most 500.
+func (b *Binance) FetchAssetDividends(asset string, startTime, endTime time.Time,
+	limit int) ([]AssetDividend, error) {
+	if !startTime.IsZero() && !endTime.IsZero() {
+		if endTime.Before(startTime) {
+			return nil, errors.New("end time must not be before the start time")
+		}
+		if endTime.Sub(startTime) > maxAssetDividendRange {
+			return nil, errors.New("time range must not exceed 90 days")
+		}
 	}
-	lastOpenOrders := b.lastOpenOrders[symbol]
-	if lastOpenOrders == nil {
-		lastOpenOrders = []Order{}
+	v := url.Values{}
+	if asset != "" {
+		v.Set("asset", asset)
 	}
-	response := []Order{}
-	err := b.SendRateLimitedHTTPRequest(10, http.MethodGet, binanceOpenOrdersPath, v,
-		RequestSecuritySign, &response, lastOpenOrders)
+	if !startTime.IsZero() {
+		v.Set("startTime", strconv.FormatInt(startTime.UnixNano()/int64(time.Millisecond), 10))
+	}
+	if !endTime.IsZero() {
+		v.Set("endTime", strconv.FormatInt(endTime.UnixNano()/int64(time.Millisecond), 10))
+	}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	response := struct {
+		Rows  []AssetDividend `json:"rows"`
+		Total int             `json:"total"`
+	}{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceAssetDividendPath, v, RequestSecuritySign, &response)
 	if err != nil {
-		return response, err
+		return nil, err
 	}
-	b.lastOpenOrders[symbol] = response
-	return response, nil
+	return response.Rows, nil
 }
 
-type PostOrderParams struct {
-	Symbol           string
-	Side             OrderSide
-	Type             OrderType
-	TimeInForce      TimeInForce
-	Quantity         float64
-	Price            float64
-	NewClientOrderID string
-	StopPrice        float64
-	IcebergQty       float64
-	// Set to true to submit the order to the test endpoint for validation,
-	// it won't be sent to the exchange matching engine.
-	ValidateOnly bool
+// RequestConvertQuote requests a quote for converting the given amount of one asset to another via
+// Binance Convert, the quote must be accepted with AcceptConvertQuote before it expires.
+func (b *Binance) RequestConvertQuote(fromAsset, toAsset string, fromAmount float64) (*ConvertQuote, error) {
+	if fromAmount <= 0 {
+		return nil, errors.New("from amount must be greater than zero")
+	}
+	v := url.Values{}
+	v.Set("fromAsset", fromAsset)
+	v.Set("toAsset", toAsset)
+	v.Set("fromAmount", strconv.FormatFloat(fromAmount, 'f', -1, 64))
+	response := ConvertQuote{}
+	_, err := b.SendHTTPRequest(http.MethodPost, binanceConvertGetQuotePath, v, RequestSecuritySign, &response)
+	return &response, err
 }
 
-func (b *Binance) PostOrderAck(params *PostOrderParams) (*PostOrderAckResponse, error) {
+// AcceptConvertQuote accepts a quote obtained via RequestConvertQuote, which executes the
+// conversion. The conversion is processed asynchronously, so the returned status may not be final.
+func (b *Binance) AcceptConvertQuote(quoteID string) (*ConvertResult, error) {
 	v := url.Values{}
-	v.Set("symbol", params.Symbol)
-	v.Set("side", string(params.Side))
-	v.Set("type", string(params.Type))
-	v.Set("timeInForce", string(params.TimeInForce))
-	v.Set("quantity", strconv.FormatFloat(params.Quantity, 'f', -1, 64))
-	v.Set("price", strconv.FormatFloat(params.Price, 'f', -1, 64))
-	if params.NewClientOrderID != "" {
-		v.Set("newClientOrderId", params.NewClientOrderID)
+	v.Set("quoteId", quoteID)
+	response := ConvertResult{}
+	_, err := b.SendHTTPRequest(http.MethodPost, binanceConvertAcceptQuotePath, v, RequestSecuritySign, &response)
+	return &response, err
+}
+
+// Ping tests connectivity to the exchange.
+func (b *Binance) Ping() error {
+	response := struct{}{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binancePingPath, nil, RequestSecurityNone, &response)
+	return err
+}
+
+// Warmup gets the client ready for trading by checking connectivity to the exchange, syncing the
+// local clock with the exchange server clock, loading the markets and validating the enabled
+// pairs (see ValidateSymbol), and (if authenticated API support is enabled) verifying the API key
+// has been granted the required permissions, which default to PermissionReading. The steps that
+// don't depend on each other run concurrently, the returned error describes every step that
+// failed.
+func (b *Binance) Warmup(requiredPermissions ...string) error {
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"ping", b.Ping},
+		{"sync time", b.SyncTime},
+		{"load markets", b.LoadMarkets},
 	}
-	if params.StopPrice != 0 {
-		v.Set("stopPrice", strconv.FormatFloat(params.StopPrice, 'f', -1, 64))
+	errs := make([]error, len(steps))
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		wg.Add(1)
+		go func(i int, run func() error) {
+			defer wg.Done()
+			errs[i] = run()
+		}(i, step.run)
 	}
-	if params.IcebergQty != 0 {
-		v.Set("icebergQty", strconv.FormatFloat(params.IcebergQty, 'f', -1, 64))
+	wg.Wait()
+
+	failed := []string{}
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", steps[i].name, err))
+		}
+	}
+	if errs[2] == nil {
+		for _, symbol := range b.EnabledPairs {
+			if err := b.ValidateSymbol(symbol); err != nil {
+				failed = append(failed, fmt.Sprintf("validate symbol: %s", err))
+			}
+		}
+	}
+	// Signed requests may be rejected until the clock has been synced.
+	if b.AuthenticatedAPISupport && errs[1] == nil {
+		if len(requiredPermissions) == 0 {
+			requiredPermissions = []string{PermissionReading}
+		}
+		if err := b.VerifyPermissions(requiredPermissions...); err != nil {
+			failed = append(failed, fmt.Sprintf("verify permissions: %s", err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("warmup failed: %s", strings.Join(failed, "; "))
 	}
-	v.Set("newOrderRespType", "ACK")
+	return nil
+}
 
-	response := PostOrderAckResponse{}
-	path := binanceOrderPath
-	if params.ValidateOnly {
-		path = binanceOrderTestPath
+// FetchServerTime fetches the current time from the exchange server.
+func (b *Binance) FetchServerTime() (time.Time, error) {
+	response := struct {
+		ServerTime int64 `json:"serverTime"`
+	}{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceServerTimePath, nil, RequestSecurityNone, &response)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, response.ServerTime*int64(time.Millisecond)), nil
+}
+
+// SyncTime measures the offset between the local clock and the exchange server clock, the
+// measured offset is applied to the timestamps of all subsequent signed requests.
+func (b *Binance) SyncTime() error {
+	requestTime := b.now()
+	serverTime, err := b.FetchServerTime()
+	if err != nil {
+		return err
+	}
+	// Assume the server time was obtained half way through the round-trip.
+	latency := b.now().Sub(requestTime)
+	offset := serverTime.Sub(requestTime.Add(latency / 2))
+	atomic.StoreInt64(&b.serverTimeOffset, int64(offset))
+	return nil
+}
+
+// ServerTimeOffset returns the offset between the local clock and the exchange server clock as
+// measured by the last call to SyncTime (zero if SyncTime hasn't been called yet).
+func (b *Binance) ServerTimeOffset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&b.serverTimeOffset))
+}
+
+// ServerTime returns the current time on the exchange server, estimated from the local time and
+// the offset measured by SyncTime.
+func (b *Binance) ServerTime() time.Time {
+	return b.now().Add(b.ServerTimeOffset())
+}
+
+// now returns the current local time as reported by nowFunc.
+func (b *Binance) now() time.Time {
+	if b.nowFunc == nil {
+		return time.Now()
 	}
-	_, err := b.SendHTTPRequest(http.MethodPost, path, v, RequestSecuritySign, &response)
+	return b.nowFunc()
+}
+
+// FetchAPIRestrictions fetches the permissions granted to the API key.
+func (b *Binance) FetchAPIRestrictions() (*APIRestrictions, error) {
+	response := APIRestrictions{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceAPIRestrictionsPath, nil, RequestSecuritySign, &response)
 	return &response, err
 }
 
-// FetchOrder fetches an order from the exchange, either orderID or clientOrderID must be provided.
-func (b *Binance) FetchOrder(symbol string, orderID int64, clientOrderID string) (*Order, error) {
-	v := url.Values{}
-	v.Set("symbol", symbol)
-	if orderID != 0 {
-		v.Set("orderId", strconv.FormatInt(orderID, 10))
+// FetchAPIKeyIPRestriction fetches the IP whitelist of the API key, the list is only enforced if
+// IPRestrict is true.
+func (b *Binance) FetchAPIKeyIPRestriction() (*IPRestriction, error) {
+	response := IPRestriction{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceIPRestrictionPath, nil, RequestSecuritySign, &response)
+	return &response, err
+}
+
+// VerifyPermissions checks that the API key has been granted all the required permissions
+// (e.g. PermissionReading, PermissionSpotAndMarginTrading), an error listing the missing
+// permissions is returned if it hasn't.
+func (b *Binance) VerifyPermissions(required ...string) error {
+	restrictions, err := b.FetchAPIRestrictions()
+	if err != nil {
+		return err
 	}
-	if clientOrderID != "" {
-		v.Set("origClientOrderId", clientOrderID)
+	permissions := restrictions.Permissions()
+	missing := []string{}
+	for _, permission := range required {
+		if !permissions[permission] {
+			missing = append(missing, permission)
+		}
 	}
-	response := Order{}
-	_, err := b.SendHTTPRequest(http.MethodGet, binanceOrderPath, v, RequestSecuritySign, &response)
+	if len(missing) > 0 {
+		return fmt.Errorf("API key is missing required permissions: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// FetchSystemStatus fetches the exchange system status, which indicates if the exchange is down
+// for maintenance.
+func (b *Binance) FetchSystemStatus() (*SystemStatus, error) {
+	response := SystemStatus{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceSystemStatusPath, nil, RequestSecurityNone, &response)
 	return &response, err
 }
 
-// DeleteOrder cancels an active order on the exchange, either orderID or clientOrderID must be provided.
-func (b *Binance) DeleteOrder(symbol string, orderID int64, clientOrderID string) error {
+// FetchPrice fetches the last price for the given symbol.
+func (b *Binance) FetchPrice(symbol string) (float64, error) {
 	v := url.Values{}
 	v.Set("symbol", symbol)
-	if orderID != 0 {
-		v.Set("orderId", strconv.FormatInt(orderID, 10))
+	response := TickerPrice{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceTickerPricePath, v, RequestSecurityNone, &response)
+	return response.Price, err
+}
+
+// FetchAllPrices fetches the last price for every symbol, the returned map is keyed by symbol.
+func (b *Binance) FetchAllPrices() (map[string]float64, error) {
+	response := []TickerPrice{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceTickerPricePath, nil, RequestSecurityNone, &response)
+	if err != nil {
+		return nil, err
 	}
-	if clientOrderID != "" {
-		v.Set("origClientOrderId", clientOrderID)
+	prices := make(map[string]float64, len(response))
+	for _, ticker := range response {
+		prices[ticker.Symbol] = ticker.Price
 	}
-	response := DeleteOrderResponse{}
-	_, err := b.SendHTTPRequest(http.MethodDelete, binanceOrderPath, v, RequestSecuritySign, &response)
-	return err
+	return prices, nil
+}
+
+// Fetch24hTicker fetches the 24 hour rolling window price change statistics for the given symbol.
+func (b *Binance) Fetch24hTicker(symbol string) (*Ticker24h, error) {
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	response := Ticker24h{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceTicker24hPath, v, RequestSecurityNone, &response)
+	return &response, err
+}
+
+// FetchAll24hTickers fetches the 24 hour rolling window price change statistics for every symbol.
+// NOTE: This request has a much higher weight than fetching the statistics for a single symbol.
+func (b *Binance) FetchAll24hTickers() ([]Ticker24h, error) {
+	response := []Ticker24h{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceTicker24hPath, nil, RequestSecurityNone, &response)
+	return response, err
+}
+
+// depthLimits are the limits supported by the depth endpoint, in ascending order.
+var depthLimits = []int64{5, 10, 20, 50, 100, 500, 1000, 5000}
+
+// supportedDepthLimit rounds the given limit up to the nearest limit supported by the depth
+// endpoint, or down to the max supported limit.
+func supportedDepthLimit(limit int64) int64 {
+	for _, supported := range depthLimits {
+		if limit <= supported {
+			return supported
+		}
+	}
+	return depthLimits[len(depthLimits)-1]
 }
 
 // FetchMarketData fetches the orderbooks for the given symbol.
-// The limit parameter can be -1, 0, 5, 10, 20, 50, 100, 200, 1000.
-// Set the limit to -1 to use the default value (currently 100), or to 0 to disable the limit
-// (this can return a lot of data, so should avoided).
+// The limit parameter can be 0 (or -1) to use the default value (currently 100), otherwise it's
+// rounded up to the nearest limit supported by Binance: 5, 10, 20, 50, 100, 500, 1000, 5000
+// (limits above 5000 are capped, large limits can return a lot of data, so should be avoided).
 // NOTE: Unlike most other exchange Binance requires a valid API key when fetching market data.
 // If this method gets rate limited it will return the market data obtained during the
 // last successful fetch, and an error matching exchange.WarningHTTPRequestRateLimited.
 func (b *Binance) FetchMarketData(symbol string, limit int64) (*MarketData, error) {
 	v := url.Values{}
 	v.Set("symbol", symbol)
-	if limit > -1 {
-		v.Set("limit", strconv.FormatInt(limit, 10))
+	if limit > 0 {
+		v.Set("limit", strconv.FormatInt(supportedDepthLimit(limit), 10))
 	}
 
 	lastMarketData := b.lastMarketData[symbol]
@@ -211,6 +1992,20 @@ func (b *Binance) FetchMarketData(symbol string, limit int64) (*MarketData, erro
 	return &response, err
 }
 
+// fetchMarketData fetches the orderbooks for the given symbol without the local throttling (and
+// fallback to the cached orderbooks) applied by FetchMarketData, for callers that must act on the
+// current orderbooks of the symbol. The limit is handled the same way as by FetchMarketData.
+func (b *Binance) fetchMarketData(symbol string, limit int64) (*MarketData, error) {
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	if limit > 0 {
+		v.Set("limit", strconv.FormatInt(supportedDepthLimit(limit), 10))
+	}
+	response := MarketData{}
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceDepthPath, v, RequestSecurityAuth, &response)
+	return &response, err
+}
+
 type RequestSecurityEnum uint8
 
 const (
@@ -225,14 +2020,55 @@ const (
 // SendAuthenticatedHTTPRequest sends a POST request to an authenticated endpoint, the response is
 // decoded into the result object.
 // Returns the Binance error code and error message (if any).
+// GET requests that fail due to network errors are retried as per the RequestBackoff policy.
 func (b *Binance) SendHTTPRequest(method, path string, params url.Values, security RequestSecurityEnum,
 	result interface{}) (int, error) {
-	if (security != RequestSecurityNone) && !b.AuthenticatedAPISupport {
-		return 0, fmt.Errorf(exchange.WarningAuthenticatedRequestWithoutCredentialsSet, b.Name)
+	resp, statusCode, err := b.SendHTTPRequestRaw(method, path, params, security)
+	if method == http.MethodGet {
+		bo := b.requestBackoff()
+		for {
+			if _, isNetErr := err.(net.Error); !isNetErr || !bo.wait(nil) {
+				break
+			}
+			b.logf("%s retrying %s request after error: %s\n", b.GetName(), path, err)
+			resp, statusCode, err = b.SendHTTPRequestRaw(method, path, params, security)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if 200 <= statusCode && statusCode <= 299 {
+		if err = common.JSONDecode([]byte(resp), &result); err != nil {
+			return statusCode, errors.New("failed to unmarshal response")
+		}
+	} else {
+		var errInfo ErrorInfo
+		if err = common.JSONDecode([]byte(resp), &errInfo); err != nil {
+			// Not an API error, most likely an HTML page served during maintenance.
+			snippet := strings.TrimSpace(resp)
+			if len(snippet) > maxErrorSnippetLength {
+				snippet = snippet[:maxErrorSnippetLength]
+			}
+			return 0, &ErrExchangeUnavailable{StatusCode: statusCode, Snippet: snippet}
+		}
+		if BinanceErrCode(errInfo.Code) == TooManyRequestsErrCode && !b.inRateLimitCooldown() {
+			b.startRateLimitCooldown("")
+		}
+		return int(errInfo.Code), errors.New(errInfo.Message)
 	}
 
-	if b.Verbose {
-		log.Printf("Request params: %v\n", params)
+	return 0, nil
+}
+
+// SendHTTPRequestRaw sends an HTTP request and returns the response body as-is along with the
+// HTTP status code, without decoding it. Unlike SendHTTPRequest a non-2xx response isn't treated
+// as an error, the caller must check the status code.
+// The path must include the path root (e.g. api/v3/ or sapi/v1/), and is appended to the API URL.
+func (b *Binance) SendHTTPRequestRaw(method, path string, params url.Values,
+	security RequestSecurityEnum) (raw string, status int, err error) {
+	if (security != RequestSecurityNone) && !b.AuthenticatedAPISupport {
+		return "", 0, fmt.Errorf(exchange.WarningAuthenticatedRequestWithoutCredentialsSet, b.Name)
 	}
 
 	headers := make(http.Header)
@@ -244,57 +2080,110 @@ func (b *Binance) SendHTTPRequest(method, path string, params url.Values, securi
 	}
 
 	if security == RequestSecuritySign {
-		recvWindow := 5000
-		// HACK: Subtract 1 sec from the real timestamp to get around incessant timestamp errors
-		// from Binance.
-		timestamp := time.Now().UnixNano()/(1000*1000) - 1000 // must be in milliseconds
-		timeWindow := fmt.Sprintf("timestamp=%v&recvWindow=%d", timestamp, recvWindow)
-		if payload != "" {
-			payload += "&" + timeWindow
-		} else {
-			payload = timeWindow
-		}
-		hmac := common.GetHMAC(common.HashSHA256, []byte(payload), []byte(b.APISecret))
-		payload = fmt.Sprintf("%s&signature=%s", payload, hex.EncodeToString(hmac))
+		payload = b.signPayload(payload)
 	}
 
 	if security != RequestSecurityNone {
 		headers["X-MBX-APIKEY"] = []string{b.APIKey}
 	}
 
-	var resp string
-	var statusCode int
-	var err error
+	b.logf("Request: %s %s payload: %s\n", method, path, redactPayload(payload))
+
+	if b.inRateLimitCooldown() {
+		return "", 0, ErrRateLimited
+	}
+
+	var respHeaders http.Header
+	startTime := time.Now()
 	if method == http.MethodGet {
-		resp, statusCode, err = common.SendHTTPRequest2(
-			method, fmt.Sprintf("%s%s?%s", binanceBaseURL, path, payload), headers, nil)
+		raw, respHeaders, status, err = common.SendHTTPRequestWithHeaders(
+			method, fmt.Sprintf("%s%s?%s", b.APIUrl, path, payload), headers, nil)
 	} else {
 		headers["Content-Type"] = []string{"application/x-www-form-urlencoded"}
-		resp, statusCode, err = common.SendHTTPRequest2(method,
-			binanceBaseURL+path, headers, strings.NewReader(payload))
+		raw, respHeaders, status, err = common.SendHTTPRequestWithHeaders(method,
+			b.APIUrl+path, headers, strings.NewReader(payload))
 	}
 
+	latency := time.Since(startTime)
+
 	if err != nil {
-		return 0, err
+		if b.OnRequest != nil {
+			b.OnRequest(method, path, 0, latency, 0)
+		}
+		return "", 0, err
 	}
 
-	if b.Verbose {
-		log.Printf("Received raw: \n%s\n", resp)
+	weight := EndpointWeight(method, path, params)
+	b.useRequestWeight(weight)
+	if b.OnRequest != nil {
+		b.OnRequest(method, path, status, latency, weight)
 	}
 
-	if 200 <= statusCode && statusCode <= 299 {
-		if err = common.JSONDecode([]byte(resp), &result); err != nil {
-			return statusCode, errors.New("failed to unmarshal response")
-		}
+	// 429 is returned when the request weight limit is exceeded, 418 when the IP has been
+	// banned for continuing to send requests after receiving a 429.
+	if status == http.StatusTooManyRequests || status == http.StatusTeapot {
+		b.startRateLimitCooldown(respHeaders.Get("Retry-After"))
+	}
+
+	b.logf("Received raw: \n%s\n", raw)
+	return raw, status, nil
+}
+
+// startRateLimitCooldown makes all requests fail with ErrRateLimited until the given number of
+// seconds elapses, or defaultRateLimitCooldown if the number of seconds isn't valid.
+func (b *Binance) startRateLimitCooldown(retryAfter string) {
+	cooldown := defaultRateLimitCooldown
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs > 0 {
+		cooldown = time.Duration(secs) * time.Second
+	}
+	b.weightMtx.Lock()
+	defer b.weightMtx.Unlock()
+	if until := time.Now().Add(cooldown); until.After(b.cooldownUntil) {
+		b.cooldownUntil = until
+	}
+}
+
+func (b *Binance) inRateLimitCooldown() bool {
+	b.weightMtx.Lock()
+	defer b.weightMtx.Unlock()
+	return time.Now().Before(b.cooldownUntil)
+}
+
+// signPayload adds a timestamp & receive window to the given URL encoded request parameters, and
+// returns them along with their signature. Only the parameters are signed, so requests to any
+// path root are signed the same way.
+func (b *Binance) signPayload(payload string) string {
+	recvWindow := 5000
+	// HACK: Subtract 1 sec from the real timestamp to get around incessant timestamp errors
+	// from Binance.
+	timestamp := b.ServerTime().UnixNano()/(1000*1000) - 1000 // must be in milliseconds
+	timeWindow := fmt.Sprintf("timestamp=%v&recvWindow=%d", timestamp, recvWindow)
+	if payload != "" {
+		payload += "&" + timeWindow
 	} else {
-		var errInfo ErrorInfo
-		if err = common.JSONDecode([]byte(resp), &errInfo); err != nil {
-			return 0, errors.New("failed to unmarshal error info")
-		}
-		return int(errInfo.Code), errors.New(errInfo.Message)
+		payload = timeWindow
 	}
+	hmac := common.GetHMAC(common.HashSHA256, []byte(payload), []byte(b.APISecret))
+	return fmt.Sprintf("%s&signature=%s", payload, hex.EncodeToString(hmac))
+}
 
-	return 0, nil
+// logf outputs verbose logging via the Binance logger, nothing is logged unless Verbose is set.
+func (b *Binance) logf(format string, v ...interface{}) {
+	if b.Verbose && b.Logger != nil {
+		b.Logger.Printf(format, v...)
+	}
+}
+
+// redactPayload returns a copy of the given request payload with the signature redacted so the
+// payload can be logged without allowing the request to be replayed.
+func redactPayload(payload string) string {
+	params := strings.Split(payload, "&")
+	for i, param := range params {
+		if strings.HasPrefix(param, "signature=") {
+			params[i] = "signature=[REDACTED]"
+		}
+	}
+	return strings.Join(params, "&")
 }
 
 // SendRateLimitedHTTPRequest sends an HTTP request if the given number of requests per minute
@@ -313,11 +2202,15 @@ func (b *Binance) SendRateLimitedHTTPRequest(requestsPerMin uint, method string,
 	if !skipRequest {
 		skipRequest = (curTimestamp - lastRequestTime) < requestDelay
 	}
+	// Don't exceed the request weight limit either.
+	if !skipRequest {
+		skipRequest = !b.requestWeightAvailable(curTimestamp, EndpointWeight(method, path, params))
+	}
 
 	if !skipRequest {
 		code, err := b.SendHTTPRequest(method, path, params, security, result)
 		if err != nil {
-			if BinanceErrCode(code) == TooManyRequestsErrCode {
+			if BinanceErrCode(code) == TooManyRequestsErrCode || err == ErrRateLimited {
 				b.ipBanStartTime = curTimestamp
 				skipRequest = true
 			} else {
@@ -350,3 +2243,94 @@ func (b *Binance) SendRateLimitedHTTPRequest(requestsPerMin uint, method string,
 
 	return nil
 }
+
+// requestWeightAvailable checks if a request of the given weight can be sent at the given
+// timestamp (in msecs) without exceeding the request weight limit.
+func (b *Binance) requestWeightAvailable(timestamp int64, weight int) bool {
+	b.weightMtx.Lock()
+	defer b.weightMtx.Unlock()
+
+	if (timestamp - b.weightWindowStart) >= (60 * 1000) {
+		return true
+	}
+	return (b.weightUsed + weight) <= binanceRequestWeightPerMin
+}
+
+// waitForRequestWeight blocks until a request of the given weight can be sent without exceeding
+// the request weight limit.
+func (b *Binance) waitForRequestWeight(weight int) {
+	for !b.requestWeightAvailable(time.Now().UnixNano()/(1000*1000), weight) {
+		time.Sleep(time.Second)
+	}
+}
+
+// RemainingOrders returns the number of orders that can currently be placed without exceeding
+// any of the order rate limits obtained by LoadMarkets, or -1 if the limits haven't been loaded.
+func (b *Binance) RemainingOrders() int {
+	b.ordersMtx.Lock()
+	defer b.ordersMtx.Unlock()
+	return b.remainingOrders(time.Now())
+}
+
+// reserveOrders records the placement of the given number of orders, or returns
+// ErrTooManyOrders if placing them would exceed one of the order rate limits.
+func (b *Binance) reserveOrders(count int) error {
+	b.ordersMtx.Lock()
+	defer b.ordersMtx.Unlock()
+
+	now := time.Now()
+	if remaining := b.remainingOrders(now); remaining != -1 && remaining < count {
+		return ErrTooManyOrders
+	}
+	for i := 0; i < count; i++ {
+		b.orderTimes = append(b.orderTimes, now)
+	}
+	return nil
+}
+
+// remainingOrders must be called with ordersMtx locked.
+func (b *Binance) remainingOrders(now time.Time) int {
+	if len(b.orderLimits) == 0 {
+		return -1
+	}
+	var maxWindow time.Duration
+	for _, limit := range b.orderLimits {
+		if limit.Window() > maxWindow {
+			maxWindow = limit.Window()
+		}
+	}
+	// Forget orders that no longer count towards any of the limits.
+	expired := sort.Search(len(b.orderTimes), func(i int) bool {
+		return now.Sub(b.orderTimes[i]) < maxWindow
+	})
+	b.orderTimes = b.orderTimes[expired:]
+
+	remaining := -1
+	for _, limit := range b.orderLimits {
+		window := limit.Window()
+		first := sort.Search(len(b.orderTimes), func(i int) bool {
+			return now.Sub(b.orderTimes[i]) < window
+		})
+		n := limit.Limit - (len(b.orderTimes) - first)
+		if n < 0 {
+			n = 0
+		}
+		if remaining == -1 || n < remaining {
+			remaining = n
+		}
+	}
+	return remaining
+}
+
+// useRequestWeight adds the given weight to the request weight used within the current window.
+func (b *Binance) useRequestWeight(weight int) {
+	b.weightMtx.Lock()
+	defer b.weightMtx.Unlock()
+
+	curTimestamp := time.Now().UnixNano() / (1000 * 1000) // convert to milliseconds
+	if (curTimestamp - b.weightWindowStart) >= (60 * 1000) {
+		b.weightWindowStart = curTimestamp
+		b.weightUsed = 0
+	}
+	b.weightUsed += weight
+}
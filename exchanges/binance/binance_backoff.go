@@ -0,0 +1,102 @@
+package binance
+
+import (
+	"math/rand"
+	"time"
+)
+
+// BackoffPolicy determines how long to wait between attempts when retrying an operation, the delay
+// grows exponentially from the base delay up to the max delay.
+type BackoffPolicy struct {
+	// Delay before the first retry.
+	BaseDelay time.Duration
+	// Upper bound on the delay between retries (before jitter is applied), zero for no bound.
+	MaxDelay time.Duration
+	// Each delay is the previous delay multiplied by this factor, values below 1 are treated as 1.
+	Multiplier float64
+	// Fraction of each delay that's randomized, e.g. 0.2 spreads the delays 20% either way.
+	Jitter float64
+	// Max number of retries, zero for no limit.
+	MaxAttempts int
+}
+
+var (
+	// DefaultStreamBackoff is used when re-establishing websocket streams, unless the Binance
+	// StreamBackoff policy is set.
+	DefaultStreamBackoff = BackoffPolicy{
+		BaseDelay:  1 * time.Second,
+		MaxDelay:   5 * time.Minute,
+		Multiplier: 2,
+		Jitter:     0.1,
+	}
+	// DefaultRequestBackoff is used when retrying idempotent requests (GET requests & order
+	// cancellations) that failed due to network errors, unless the Binance RequestBackoff policy
+	// is set.
+	DefaultRequestBackoff = BackoffPolicy{
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		Multiplier:  2,
+		Jitter:      0.1,
+		MaxAttempts: 3,
+	}
+)
+
+// Delay returns the delay before the given retry, retries are numbered from 1.
+func (p BackoffPolicy) Delay(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.BaseDelay)
+	for i := 1; i < retry && (p.MaxDelay <= 0 || delay < float64(p.MaxDelay)); i++ {
+		delay *= multiplier
+	}
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// backoff keeps track of the retries of a single operation.
+type backoff struct {
+	policy  BackoffPolicy
+	retries int
+}
+
+func newBackoff(policy BackoffPolicy) *backoff {
+	return &backoff{policy: policy}
+}
+
+// wait waits before the next retry. Returns false without waiting if the max number of retries
+// has been reached, or returns false as soon as the done channel is closed (it may be nil).
+func (bo *backoff) wait(done <-chan struct{}) bool {
+	if bo.policy.MaxAttempts > 0 && bo.retries >= bo.policy.MaxAttempts {
+		return false
+	}
+	bo.retries++
+	select {
+	case <-done:
+		return false
+	case <-time.After(bo.policy.Delay(bo.retries)):
+		return true
+	}
+}
+
+// streamBackoff returns a new backoff for re-establishing a websocket stream.
+func (b *Binance) streamBackoff() *backoff {
+	if b.StreamBackoff != nil {
+		return newBackoff(*b.StreamBackoff)
+	}
+	return newBackoff(DefaultStreamBackoff)
+}
+
+// requestBackoff returns a new backoff for retrying a request.
+func (b *Binance) requestBackoff() *backoff {
+	if b.RequestBackoff != nil {
+		return newBackoff(*b.RequestBackoff)
+	}
+	return newBackoff(DefaultRequestBackoff)
+}
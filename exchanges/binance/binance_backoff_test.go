@@ -0,0 +1,34 @@
+package binance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffPolicy(t *testing.T) {
+	t.Parallel()
+	policy := BackoffPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, Multiplier: 3}
+	expected := []time.Duration{time.Second, 3 * time.Second, 9 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, delay := range expected {
+		if d := policy.Delay(i + 1); d != delay {
+			t.Errorf("Test failed. Delay(%d) returned %v, expected %v", i+1, d, delay)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := policy.Delay(2); d < 1500*time.Millisecond || d > 4500*time.Millisecond {
+			t.Fatalf("Test failed. Delay(2) with jitter returned %v", d)
+		}
+	}
+
+	bo := newBackoff(BackoffPolicy{BaseDelay: time.Millisecond, MaxAttempts: 2})
+	if !bo.wait(nil) || !bo.wait(nil) || bo.wait(nil) {
+		t.Error("Test failed. backoff didn't stop after the max number of attempts")
+	}
+	done := make(chan struct{})
+	close(done)
+	if newBackoff(BackoffPolicy{BaseDelay: time.Hour}).wait(done) {
+		t.Error("Test failed. backoff waited after being stopped")
+	}
+}
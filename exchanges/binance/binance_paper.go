@@ -0,0 +1,395 @@
+package binance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mattkanwisher/cryptofiend/exchanges"
+	"github.com/shopspring/decimal"
+)
+
+var errPaperOrderNotFound = errors.New("Order does not exist.")
+
+// PaperBinance is a Client that simulates order placement locally instead of sending orders to
+// the exchange. Orders are filled against the orderbooks fetched via the wrapped Binance client,
+// balances are tracked locally, and no commission is charged.
+//
+// MARKET and LIMIT orders are supported. The part of an order that can be filled immediately is
+// filled at the prices of the orderbook levels it consumes, the rest of a GTC LIMIT order rests
+// on the book and is filled at the limit price once FetchMarketData returns an orderbook that
+// crosses the limit price.
+type PaperBinance struct {
+	b           *Binance
+	mtx         sync.Mutex
+	balances    map[string]*paperBalance
+	orders      []*Order
+	nextOrderID int64
+}
+
+type paperBalance struct {
+	free, locked decimal.Decimal
+}
+
+var _ Client = (*PaperBinance)(nil)
+
+// NewPaperBinance returns a PaperBinance client that starts out with the given free balances,
+// keyed by asset. The markets of the given client must have been loaded.
+func NewPaperBinance(b *Binance, balances map[string]float64) *PaperBinance {
+	p := &PaperBinance{
+		b:           b,
+		balances:    make(map[string]*paperBalance, len(balances)),
+		nextOrderID: 1,
+	}
+	for asset, free := range balances {
+		p.balances[asset] = &paperBalance{free: decimal.NewFromFloat(free)}
+	}
+	return p
+}
+
+// FetchAccountInfo returns the simulated balances.
+func (p *PaperBinance) FetchAccountInfo() (*AccountInfo, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	info := &AccountInfo{CanTrade: true}
+	for asset, balance := range p.balances {
+		free, _ := balance.free.Float64()
+		locked, _ := balance.locked.Float64()
+		info.Balances = append(info.Balances, &Balance{Asset: asset, Free: free, Locked: locked})
+	}
+	sort.Slice(info.Balances, func(i, j int) bool { return info.Balances[i].Asset < info.Balances[j].Asset })
+	return info, nil
+}
+
+// PostOrderAck simulates placing an order.
+func (p *PaperBinance) PostOrderAck(params *PostOrderParams) (*PostOrderAckResponse, error) {
+	order, _, err := p.placeOrder(params)
+	if err != nil {
+		return &PostOrderAckResponse{}, err
+	}
+	return &PostOrderAckResponse{
+		Symbol:        order.Symbol,
+		OrderID:       order.OrderID,
+		ClientOrderID: order.ClientOrderID,
+		TransactTime:  order.Time,
+	}, nil
+}
+
+// PostOrderFull simulates placing an order, and returns the simulated fills.
+func (p *PaperBinance) PostOrderFull(params *PostOrderParams) (*PostOrderFullResponse, error) {
+	order, fills, err := p.placeOrder(params)
+	if err != nil {
+		return &PostOrderFullResponse{}, err
+	}
+	response := &PostOrderFullResponse{
+		Symbol:        order.Symbol,
+		OrderID:       order.OrderID,
+		ClientOrderID: order.ClientOrderID,
+		TransactTime:  order.Time,
+		Price:         order.Price,
+		OrigQty:       order.OrigQty,
+		ExecutedQty:   order.ExecutedQty,
+		Status:        order.Status,
+		TimeInForce:   order.TimeInForce,
+		Type:          order.Type,
+		Side:          order.Side,
+		Fills:         fills,
+	}
+	for _, fill := range fills {
+		response.CummulativeQuoteQty, _ = decimal.NewFromFloat(response.CummulativeQuoteQty).
+			Add(decimal.NewFromFloat(fill.Price).Mul(decimal.NewFromFloat(fill.Qty))).Float64()
+	}
+	return response, nil
+}
+
+// FetchOrder returns a simulated order, either orderID or clientOrderID must be provided.
+func (p *PaperBinance) FetchOrder(symbol string, orderID int64, clientOrderID string) (*Order, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	order := p.findOrder(symbol, orderID, clientOrderID)
+	if order == nil {
+		return &Order{}, errPaperOrderNotFound
+	}
+	result := *order
+	return &result, nil
+}
+
+// DeleteOrder cancels a simulated open order, either orderID or clientOrderID must be provided.
+func (p *PaperBinance) DeleteOrder(symbol string, orderID int64, clientOrderID string) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	order := p.findOrder(symbol, orderID, clientOrderID)
+	if order == nil || !order.IsWorking {
+		return errPaperOrderNotFound
+	}
+	info, err := p.b.getSymbolInfo(order.Symbol)
+	if err != nil {
+		return err
+	}
+	remaining := decimal.NewFromFloat(order.OrigQty).Sub(decimal.NewFromFloat(order.ExecutedQty))
+	if order.Side == OrderSideBuy {
+		p.unlock(info.QuoteAsset, remaining.Mul(decimal.NewFromFloat(order.Price)))
+	} else {
+		p.unlock(info.BaseAsset, remaining)
+	}
+	order.Status = OrderStatusCanceled
+	order.IsWorking = false
+	return nil
+}
+
+// FetchOpenOrders returns the simulated open orders for the given symbol, or for all symbols if
+// the symbol is blank.
+func (p *PaperBinance) FetchOpenOrders(symbol string) ([]Order, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	result := []Order{}
+	for _, order := range p.orders {
+		if order.IsWorking && (symbol == "" || order.Symbol == symbol) {
+			result = append(result, *order)
+		}
+	}
+	return result, nil
+}
+
+// FetchMarketData fetches the orderbooks for the given symbol from the exchange, and fills any
+// resting orders for the symbol that the orderbook crosses. Resting orders aren't filled when the
+// fetch is rate limited, since the returned orderbooks are then those of an earlier fetch.
+func (p *PaperBinance) FetchMarketData(symbol string, limit int64) (*MarketData, error) {
+	marketData, err := p.b.FetchMarketData(symbol, limit)
+	if err != nil {
+		return marketData, err
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if err = p.matchRestingOrders(symbol, marketData); err != nil {
+		return marketData, err
+	}
+	return marketData, nil
+}
+
+func (p *PaperBinance) placeOrder(params *PostOrderParams) (*Order, []OrderFill, error) {
+	info, err := p.b.getSymbolInfo(params.Symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+	if params.Side != OrderSideBuy && params.Side != OrderSideSell {
+		return nil, nil, fmt.Errorf("invalid order side '%s'", params.Side)
+	}
+	if params.Type != OrderTypeMarket && params.Type != OrderTypeLimit {
+		return nil, nil, fmt.Errorf("order type %s isn't supported in paper trading", params.Type)
+	}
+	if params.Quantity <= 0 {
+		return nil, nil, errors.New("order quantity must be greater than zero")
+	}
+	if params.Type == OrderTypeLimit && params.Price <= 0 {
+		return nil, nil, errors.New("order price must be greater than zero")
+	}
+	if params.ValidateOnly {
+		return &Order{}, nil, nil
+	}
+
+	// The orderbook is fetched directly rather than via FetchMarketData, which may return the
+	// cached orderbook of an earlier request when it's throttled.
+	marketData, err := p.b.fetchMarketData(params.Symbol, 100)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	levels := marketData.Asks
+	if params.Side == OrderSideSell {
+		levels = marketData.Bids
+	}
+	if (len(marketData.Bids) == 0 && len(marketData.Asks) == 0) ||
+		(params.Type == OrderTypeMarket && len(levels) == 0) {
+		return nil, nil, fmt.Errorf("orderbook for '%s' symbol is empty", params.Symbol)
+	}
+	quantity := decimal.NewFromFloat(params.Quantity)
+	limitPrice := decimal.NewFromFloat(params.Price)
+
+	// Work out what can be filled immediately.
+	var fills []OrderFill
+	filledQty := decimal.Zero
+	filledQuote := decimal.Zero
+	for _, level := range levels {
+		if params.Type == OrderTypeLimit &&
+			((params.Side == OrderSideBuy && level.Price > params.Price) ||
+				(params.Side == OrderSideSell && level.Price < params.Price)) {
+			break
+		}
+		qty := decimal.Min(quantity.Sub(filledQty), decimal.NewFromFloat(level.Quantity))
+		if !qty.IsPositive() {
+			break
+		}
+		filledQty = filledQty.Add(qty)
+		filledQuote = filledQuote.Add(qty.Mul(decimal.NewFromFloat(level.Price)))
+		fillQty, _ := qty.Float64()
+		fills = append(fills, OrderFill{Price: level.Price, Qty: fillQty})
+	}
+	if params.TimeInForce == TimeInForceFOK && filledQty.LessThan(quantity) {
+		fills = nil
+		filledQty = decimal.Zero
+		filledQuote = decimal.Zero
+	}
+	resting := decimal.Zero
+	if params.Type == OrderTypeLimit && (params.TimeInForce == "" || params.TimeInForce == TimeInForceGTC) {
+		resting = quantity.Sub(filledQty)
+	}
+
+	// Make sure the balances can cover the order before changing anything.
+	if params.Side == OrderSideBuy {
+		required := filledQuote.Add(resting.Mul(limitPrice))
+		if p.balance(info.QuoteAsset).free.LessThan(required) {
+			return nil, nil, exchange.ErrInsufficentFundsForOrder()
+		}
+		p.balance(info.QuoteAsset).free = p.balance(info.QuoteAsset).free.Sub(filledQuote)
+		p.balance(info.BaseAsset).free = p.balance(info.BaseAsset).free.Add(filledQty)
+		p.lock(info.QuoteAsset, resting.Mul(limitPrice))
+	} else {
+		if p.balance(info.BaseAsset).free.LessThan(filledQty.Add(resting)) {
+			return nil, nil, exchange.ErrInsufficentFundsForOrder()
+		}
+		p.balance(info.BaseAsset).free = p.balance(info.BaseAsset).free.Sub(filledQty)
+		p.balance(info.QuoteAsset).free = p.balance(info.QuoteAsset).free.Add(filledQuote)
+		p.lock(info.BaseAsset, resting)
+	}
+
+	order := &Order{
+		Symbol:        params.Symbol,
+		OrderID:       p.nextOrderID,
+		ClientOrderID: params.NewClientOrderID,
+		Price:         params.Price,
+		OrigQty:       params.Quantity,
+		TimeInForce:   params.TimeInForce,
+		Type:          params.Type,
+		Side:          params.Side,
+		Time:          time.Now().UnixNano() / int64(time.Millisecond),
+		IsWorking:     resting.IsPositive(),
+	}
+	if order.ClientOrderID == "" {
+		order.ClientOrderID = fmt.Sprintf("paper-%d", order.OrderID)
+	}
+	order.ExecutedQty, _ = filledQty.Float64()
+	switch {
+	case filledQty.Equal(quantity):
+		order.Status = OrderStatusFilled
+	case resting.IsPositive() && filledQty.IsPositive():
+		order.Status = OrderStatusPartial
+	case resting.IsPositive():
+		order.Status = OrderStatusNew
+	default:
+		order.Status = OrderStatusExpired
+	}
+	for i := range fills {
+		fills[i].TradeID = order.OrderID
+	}
+	p.nextOrderID++
+	p.orders = append(p.orders, order)
+
+	result := *order
+	return &result, fills, nil
+}
+
+// matchRestingOrders fills the resting orders of the given symbol that the given orderbook
+// crosses, at the limit price of each order. Must be called with mtx locked.
+func (p *PaperBinance) matchRestingOrders(symbol string, marketData *MarketData) error {
+	var info *SymbolInfo
+	// Each level can only be consumed once.
+	asks := append([]OrderbookEntry(nil), marketData.Asks...)
+	bids := append([]OrderbookEntry(nil), marketData.Bids...)
+	for _, order := range p.orders {
+		if !order.IsWorking || order.Symbol != symbol {
+			continue
+		}
+		if info == nil {
+			var err error
+			if info, err = p.b.getSymbolInfo(symbol); err != nil {
+				return err
+			}
+		}
+		levels := asks
+		if order.Side == OrderSideSell {
+			levels = bids
+		}
+		price := decimal.NewFromFloat(order.Price)
+		remaining := decimal.NewFromFloat(order.OrigQty).Sub(decimal.NewFromFloat(order.ExecutedQty))
+		filled := decimal.Zero
+		for i := range levels {
+			if (order.Side == OrderSideBuy && levels[i].Price > order.Price) ||
+				(order.Side == OrderSideSell && levels[i].Price < order.Price) {
+				break
+			}
+			levelQty := decimal.NewFromFloat(levels[i].Quantity)
+			qty := decimal.Min(remaining.Sub(filled), levelQty)
+			if !qty.IsPositive() {
+				continue
+			}
+			filled = filled.Add(qty)
+			levels[i].Quantity, _ = levelQty.Sub(qty).Float64()
+		}
+		if !filled.IsPositive() {
+			continue
+		}
+
+		if order.Side == OrderSideBuy {
+			p.balance(info.QuoteAsset).locked = p.balance(info.QuoteAsset).locked.Sub(filled.Mul(price))
+			p.balance(info.BaseAsset).free = p.balance(info.BaseAsset).free.Add(filled)
+		} else {
+			p.balance(info.BaseAsset).locked = p.balance(info.BaseAsset).locked.Sub(filled)
+			p.balance(info.QuoteAsset).free = p.balance(info.QuoteAsset).free.Add(filled.Mul(price))
+		}
+		order.ExecutedQty, _ = decimal.NewFromFloat(order.ExecutedQty).Add(filled).Float64()
+		if filled.Equal(remaining) {
+			order.Status = OrderStatusFilled
+			order.IsWorking = false
+		} else {
+			order.Status = OrderStatusPartial
+		}
+	}
+	return nil
+}
+
+// findOrder must be called with mtx locked.
+func (p *PaperBinance) findOrder(symbol string, orderID int64, clientOrderID string) *Order {
+	for _, order := range p.orders {
+		if order.Symbol == symbol && ((orderID != 0 && order.OrderID == orderID) ||
+			(orderID == 0 && clientOrderID != "" && order.ClientOrderID == clientOrderID)) {
+			return order
+		}
+	}
+	return nil
+}
+
+// balance returns the balance of the given asset, creating it if necessary. Must be called with
+// mtx locked.
+func (p *PaperBinance) balance(asset string) *paperBalance {
+	balance, exists := p.balances[asset]
+	if !exists {
+		balance = &paperBalance{}
+		p.balances[asset] = balance
+	}
+	return balance
+}
+
+// lock moves the given amount of an asset from the free to the locked balance. Must be called
+// with mtx locked.
+func (p *PaperBinance) lock(asset string, amount decimal.Decimal) {
+	balance := p.balance(asset)
+	balance.free = balance.free.Sub(amount)
+	balance.locked = balance.locked.Add(amount)
+}
+
+// unlock moves the given amount of an asset from the locked to the free balance. Must be called
+// with mtx locked.
+func (p *PaperBinance) unlock(asset string, amount decimal.Decimal) {
+	p.lock(asset, amount.Neg())
+}
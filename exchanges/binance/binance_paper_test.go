@@ -0,0 +1,188 @@
+package binance
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mattkanwisher/cryptofiend/exchanges"
+)
+
+func TestPaperBinance(t *testing.T) {
+	t.Parallel()
+	depth := `{"lastUpdateId":1,"bids":[["0.00100000","10.00000000",[]]],
+		"asks":[["0.00200000","10.00000000",[]],["0.00300000","10.00000000",[]]]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + binanceExchangeInfoPath:
+			fmt.Fprint(w, testExchangeInfo)
+		case "/" + binanceDepthPath:
+			fmt.Fprint(w, depth)
+		default:
+			t.Errorf("Test failed. Unexpected request to %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if err := b.LoadMarkets(); err != nil {
+		t.Fatalf("Test failed. LoadMarkets returned error: %s", err)
+	}
+	p := NewPaperBinance(b, map[string]float64{"BTC": 1})
+	checkBalances := func(step string, expected map[string][2]float64) {
+		info, err := p.FetchAccountInfo()
+		if err != nil {
+			t.Fatalf("Test failed. %s: FetchAccountInfo returned error: %s", step, err)
+		}
+		for _, balance := range info.Balances {
+			if e := expected[balance.Asset]; balance.Free != e[0] || balance.Locked != e[1] {
+				t.Errorf("Test failed. %s: unexpected %s balance %v/%v", step, balance.Asset,
+					balance.Free, balance.Locked)
+			}
+		}
+	}
+
+	full, err := p.PostOrderFull(&PostOrderParams{
+		Symbol: "BNBBTC", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 15,
+	})
+	if err != nil {
+		t.Fatalf("Test failed. PostOrderFull returned error: %s", err)
+	}
+	if full.Status != OrderStatusFilled || full.ExecutedQty != 15 || len(full.Fills) != 2 ||
+		full.CummulativeQuoteQty != 0.035 {
+		t.Errorf("Test failed. Unexpected market order response %+v", full)
+	}
+	checkBalances("market buy", map[string][2]float64{"BTC": {0.965, 0}, "BNB": {15, 0}})
+
+	ack, err := p.PostOrderAck(&PostOrderParams{
+		Symbol: "BNBBTC", Side: OrderSideSell, Type: OrderTypeLimit, TimeInForce: TimeInForceGTC,
+		Quantity: 5, Price: 0.0015, NewClientOrderID: "resting",
+	})
+	if err != nil {
+		t.Fatalf("Test failed. PostOrderAck returned error: %s", err)
+	}
+	checkBalances("limit sell", map[string][2]float64{"BTC": {0.965, 0}, "BNB": {10, 5}})
+	if orders, _ := p.FetchOpenOrders("BNBBTC"); len(orders) != 1 || orders[0].OrderID != ack.OrderID ||
+		orders[0].Status != OrderStatusNew {
+		t.Errorf("Test failed. Unexpected open orders %+v", orders)
+	}
+
+	full, err = p.PostOrderFull(&PostOrderParams{
+		Symbol: "BNBBTC", Side: OrderSideBuy, Type: OrderTypeLimit, TimeInForce: TimeInForceFOK,
+		Quantity: 100, Price: 0.003,
+	})
+	if err != nil || full.Status != OrderStatusExpired || len(full.Fills) != 0 {
+		t.Errorf("Test failed. Unexpected FOK order response %+v, error %v", full, err)
+	}
+	if _, err = p.PostOrderAck(&PostOrderParams{
+		Symbol: "BNBBTC", Side: OrderSideBuy, Type: OrderTypeLimit, TimeInForce: TimeInForceGTC,
+		Quantity: 1000, Price: 0.002,
+	}); err == nil {
+		t.Error("Test failed. Expected an error for an order exceeding the balance")
+	}
+	if _, err = p.PostOrderAck(&PostOrderParams{
+		Symbol: "BNBBTC", Side: OrderSideSell, Type: OrderTypeStopLossLimit, Quantity: 1, Price: 0.001,
+	}); err == nil {
+		t.Error("Test failed. Expected an error for an unsupported order type")
+	}
+	checkBalances("rejected orders", map[string][2]float64{"BTC": {0.965, 0}, "BNB": {10, 5}})
+
+	// A rate limited fetch returns the cached orderbook, which crosses the resting order even though
+	// the market has since moved away from it, so the resting order must not be filled.
+	nonCrossing := depth
+	depth = `{"lastUpdateId":2,"bids":[["0.00160000","3.00000000",[]]],"asks":[]}`
+	if _, err = b.FetchMarketData("BNBBTC", -1); err != nil {
+		t.Fatalf("Test failed. FetchMarketData returned error: %s", err)
+	}
+	depth = nonCrossing
+	if _, err = p.FetchMarketData("BNBBTC", -1); err != exchange.WarningHTTPRequestRateLimited() {
+		t.Fatalf("Test failed. Expected FetchMarketData to be rate limited, got %v", err)
+	}
+	checkBalances("rate limited fetch", map[string][2]float64{"BTC": {0.965, 0}, "BNB": {10, 5}})
+
+	// Skip the request spacing so the new orderbook is fetched rather than the cached one.
+	b.rateLimits = map[string]int64{}
+	depth = `{"lastUpdateId":2,"bids":[["0.00160000","3.00000000",[]]],"asks":[]}`
+	if _, err = p.FetchMarketData("BNBBTC", -1); err != nil {
+		t.Fatalf("Test failed. FetchMarketData returned error: %s", err)
+	}
+	checkBalances("partial fill", map[string][2]float64{"BTC": {0.9695, 0}, "BNB": {10, 2}})
+	order, err := p.FetchOrder("BNBBTC", 0, "resting")
+	if err != nil || order.Status != OrderStatusPartial || order.ExecutedQty != 3 {
+		t.Errorf("Test failed. Unexpected order %+v, error %v", order, err)
+	}
+
+	if err = p.DeleteOrder("BNBBTC", ack.OrderID, ""); err != nil {
+		t.Fatalf("Test failed. DeleteOrder returned error: %s", err)
+	}
+	checkBalances("cancel", map[string][2]float64{"BTC": {0.9695, 0}, "BNB": {12, 0}})
+	if order, _ = p.FetchOrder("BNBBTC", ack.OrderID, ""); order.Status != OrderStatusCanceled || order.IsWorking {
+		t.Errorf("Test failed. Unexpected canceled order %+v", order)
+	}
+	if err = p.DeleteOrder("BNBBTC", ack.OrderID, ""); err == nil {
+		t.Error("Test failed. Expected an error when canceling a canceled order")
+	}
+	if orders, _ := p.FetchOpenOrders(""); len(orders) != 0 {
+		t.Errorf("Test failed. Unexpected open orders %+v", orders)
+	}
+}
+
+func TestPaperBinanceOrderbookFetch(t *testing.T) {
+	t.Parallel()
+	var mtx sync.Mutex
+	depths := map[string]string{
+		"BNBBTC": `{"lastUpdateId":1,"bids":[["0.00100000","10.00000000"]],"asks":[["0.00200000","10.00000000"]]}`,
+		"XYZBTC": `{"lastUpdateId":1,"bids":[["0.00010000","10.00000000"]],"asks":[["0.00020000","10.00000000"]]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + binanceExchangeInfoPath:
+			fmt.Fprint(w, testExchangeInfo)
+		case "/" + binanceDepthPath:
+			mtx.Lock()
+			defer mtx.Unlock()
+			fmt.Fprint(w, depths[r.URL.Query().Get("symbol")])
+		default:
+			t.Errorf("Test failed. Unexpected request to %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if err := b.LoadMarkets(); err != nil {
+		t.Fatalf("Test failed. LoadMarkets returned error: %s", err)
+	}
+	p := NewPaperBinance(b, map[string]float64{"BTC": 1})
+
+	// Orders placed in quick succession are each filled against their own symbol's orderbook.
+	for _, tc := range []struct {
+		symbol string
+		price  float64
+	}{{"BNBBTC", 0.002}, {"XYZBTC", 0.0002}, {"BNBBTC", 0.002}} {
+		full, err := p.PostOrderFull(&PostOrderParams{
+			Symbol: tc.symbol, Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 1,
+		})
+		if err != nil {
+			t.Fatalf("Test failed. PostOrderFull returned error: %s", err)
+		}
+		if full.Status != OrderStatusFilled || len(full.Fills) != 1 || full.Fills[0].Price != tc.price {
+			t.Errorf("Test failed. Unexpected %s market order response %+v", tc.symbol, full)
+		}
+	}
+
+	mtx.Lock()
+	depths["BNBBTC"] = `{"lastUpdateId":2,"bids":[],"asks":[]}`
+	mtx.Unlock()
+	for _, orderType := range []OrderType{OrderTypeMarket, OrderTypeLimit} {
+		if _, err := p.PostOrderFull(&PostOrderParams{
+			Symbol: "BNBBTC", Side: OrderSideBuy, Type: orderType, TimeInForce: TimeInForceGTC, Quantity: 1,
+			Price: 0.001,
+		}); err == nil {
+			t.Errorf("Test failed. Expected an error for a %s order against an empty orderbook", orderType)
+		}
+	}
+}
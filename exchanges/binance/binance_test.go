@@ -0,0 +1,2155 @@
+package binance
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattkanwisher/cryptofiend/exchanges/orderbook"
+)
+
+// testLogger captures everything logged to it, it can be logged to from multiple goroutines.
+type testLogger struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	fmt.Fprintf(&l.buf, format, v...)
+}
+
+func (l *testLogger) String() string {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.buf.String()
+}
+
+// newTestBinance returns a verbose, authenticated Binance instance that sends all requests to
+// the given test server.
+func newTestBinance(server *httptest.Server) *Binance {
+	b := &Binance{}
+	b.SetDefaults()
+	b.APIUrl = server.URL + "/"
+	b.AuthenticatedAPISupport = true
+	b.APIKey = "testAPIKey"
+	b.APISecret = "testAPISecret"
+	b.Verbose = true
+	b.Logger = &testLogger{}
+	return b
+}
+
+const testExchangeInfo = `{
+	"timezone": "UTC",
+	"serverTime": 1565246363776,
+	"exchangeFilters": [{"filterType": "EXCHANGE_MAX_NUM_ORDERS", "maxNumOrders": 1000}],
+	"symbols": [{
+		"symbol": "BNBBTC",
+		"status": "TRADING",
+		"baseAsset": "BNB",
+		"baseAssetPrecision": 8,
+		"quoteAsset": "BTC",
+		"quoteAssetPrecision": 8,
+		"orderTypes": ["LIMIT", "LIMIT_MAKER", "MARKET", "STOP_LOSS_LIMIT", "TAKE_PROFIT_LIMIT"],
+		"icebergAllowed": true,
+		"quoteOrderQtyMarketAllowed": true,
+		"permissions": ["SPOT", "MARGIN"],
+		"allowedSelfTradePreventionModes": ["NONE", "EXPIRE_TAKER", "EXPIRE_BOTH"],
+		"defaultSelfTradePreventionMode": "NONE",
+		"filters": [
+			{"filterType": "PRICE_FILTER", "minPrice": "0.00000100", "maxPrice": "100000.00000000", "tickSize": "0.00000100"},
+			{"filterType": "LOT_SIZE", "minQty": "0.01000000", "maxQty": "9000000.00000000", "stepSize": "0.01000000"},
+			{"filterType": "MIN_NOTIONAL", "minNotional": "0.00100000"}
+		]
+	}, {
+		"symbol": "XYZBTC",
+		"status": "BREAK",
+		"baseAsset": "XYZ",
+		"baseAssetPrecision": 8,
+		"quoteAsset": "BTC",
+		"quoteAssetPrecision": 8,
+		"orderTypes": ["LIMIT"],
+		"icebergAllowed": false,
+		"permissions": ["MARGIN"],
+		"filters": [
+			{"filterType": "PRICE_FILTER", "minPrice": "0.00000001", "maxPrice": "1000.00000000", "tickSize": "0.00000001"},
+			{"filterType": "LOT_SIZE", "minQty": "1.00000000", "maxQty": "90000000.00000000", "stepSize": "1.00000000"},
+			{"filterType": "NOTIONAL", "minNotional": "0.00010000", "maxNotional": "9000000.00000000"}
+		]
+	}]
+}`
+
+// newTestMarketsBinance returns a Binance instance with markets loaded from testExchangeInfo.
+func newTestMarketsBinance(t *testing.T) *Binance {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testExchangeInfo)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if err := b.LoadMarkets(); err != nil {
+		t.Fatalf("Test failed. LoadMarkets returned error: %s", err)
+	}
+	return b
+}
+
+func TestEndpointWeight(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		method string
+		path   string
+		params url.Values
+		weight int
+	}{
+		{http.MethodGet, binanceDepthPath, url.Values{}, 1},
+		{http.MethodGet, binanceDepthPath, url.Values{"limit": {"100"}}, 1},
+		{http.MethodGet, binanceDepthPath, url.Values{"limit": {"500"}}, 5},
+		{http.MethodGet, binanceDepthPath, url.Values{"limit": {"1000"}}, 10},
+		{http.MethodGet, binanceDepthPath, url.Values{"limit": {"5000"}}, 50},
+		{http.MethodDelete, binanceOpenOrdersPath, url.Values{"symbol": {"BNBBTC"}}, 1},
+		{http.MethodGet, binanceTickerPricePath, url.Values{}, 2},
+		{http.MethodGet, binanceTickerPricePath, url.Values{"symbol": {"BNBBTC"}}, 1},
+		{http.MethodGet, binanceTicker24hPath, url.Values{}, 40},
+		{http.MethodGet, binanceTicker24hPath, url.Values{"symbol": {"BNBBTC"}}, 1},
+		{http.MethodGet, binanceDepthPath, url.Values{"limit": {"0"}}, 50},
+		{http.MethodGet, binancePreventedMatchesPath, url.Values{"preventedMatchId": {"1"}}, 2},
+		{http.MethodGet, binancePreventedMatchesPath, url.Values{"orderId": {"1"}}, 20},
+		{http.MethodGet, binanceOpenOrdersPath, url.Values{}, 40},
+		{http.MethodGet, binanceOpenOrdersPath, url.Values{"symbol": {"BNBBTC"}}, 3},
+		{http.MethodGet, binanceAccountPath, nil, 10},
+		{http.MethodPost, binanceOrderPath, nil, 1},
+		{http.MethodGet, "api/v3/unknown", nil, 1},
+	}
+	for _, test := range tests {
+		if w := EndpointWeight(test.method, test.path, test.params); w != test.weight {
+			t.Errorf("Test failed. EndpointWeight(%s, %s, %v) = %d, expected %d",
+				test.method, test.path, test.params, w, test.weight)
+		}
+	}
+}
+
+func TestVerboseLoggingRedactsSecrets(t *testing.T) {
+	t.Parallel()
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.URL.Query().Get("signature")
+		fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":1}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	_, err := b.SendHTTPRequest(http.MethodGet, binanceOrderPath, url.Values{"symbol": {"BNBBTC"}},
+		RequestSecuritySign, &Order{})
+	if err != nil {
+		t.Fatalf("Test failed. SendHTTPRequest returned error: %s", err)
+	}
+
+	logged := b.Logger.(*testLogger).String()
+	if !strings.Contains(logged, "symbol=BNBBTC") {
+		t.Error("Test failed. Expected the request payload to be logged")
+	}
+	if signature == "" || strings.Contains(logged, signature) {
+		t.Error("Test failed. Logged request contains the signature")
+	}
+	if strings.Contains(logged, b.APIKey) {
+		t.Error("Test failed. Logged request contains the API key")
+	}
+}
+
+func TestOrderDecodesTypedEnums(t *testing.T) {
+	t.Parallel()
+	data := `{"symbol":"LTCBTC","orderId":1,"clientOrderId":"myOrder1","price":"0.1",
+		"origQty":"1.0","executedQty":"0.0","status":"NEW","timeInForce":"GTC","type":"LIMIT",
+		"side":"BUY","stopPrice":"0.0","icebergQty":"0.0","time":1499827319559,"isWorking":true}`
+	order := Order{}
+	if err := json.Unmarshal([]byte(data), &order); err != nil {
+		t.Fatalf("Test failed. Failed to decode order: %s", err)
+	}
+	params := PostOrderParams{
+		Symbol:      order.Symbol,
+		Side:        order.Side,
+		Type:        order.Type,
+		TimeInForce: order.TimeInForce,
+		Quantity:    order.OrigQty,
+		Price:       order.Price,
+	}
+	if params.Side != OrderSideBuy || params.Type != OrderTypeLimit ||
+		params.TimeInForce != TimeInForceGTC || order.Status != OrderStatusNew {
+		t.Errorf("Test failed. Order enums decoded incorrectly: %+v", order)
+	}
+}
+
+func TestExecutionReportEventDecode(t *testing.T) {
+	t.Parallel()
+	// Binance's documented payload, several keys only differ by case.
+	data := `{"e":"executionReport","E":1499405658658,"s":"ETHBTC","c":"mUvoqJxFIILMdfAW5iGSOW",
+		"S":"BUY","o":"LIMIT","f":"GTC","q":"1.00000000","p":"0.10264410","P":"0.00000000",
+		"F":"0.00000000","g":-1,"C":"","x":"NEW","X":"NEW","r":"NONE","i":4293153,"l":"0.00000000",
+		"z":"0.00000000","L":"0.00000000","n":"0","N":null,"T":1499405658657,"t":-1,"v":3,
+		"I":8641984,"w":true,"m":false,"M":false,"O":1499405658657,"Z":"0.00000000",
+		"Y":"0.00000000","Q":"0.00000000","W":1499405658657,"V":"NONE"}`
+	report := ExecutionReportEvent{}
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		t.Fatalf("Test failed. Failed to decode execution report: %s", err)
+	}
+	expected := ExecutionReportEvent{
+		EventType:         UserDataEventExecutionReport,
+		EventTime:         1499405658658,
+		Symbol:            "ETHBTC",
+		ClientOrderID:     "mUvoqJxFIILMdfAW5iGSOW",
+		Side:              OrderSideBuy,
+		Type:              OrderTypeLimit,
+		TimeInForce:       TimeInForceGTC,
+		Quantity:          1,
+		Price:             0.1026441,
+		ExecutionType:     "NEW",
+		Status:            OrderStatusNew,
+		OrderID:           4293153,
+		TransactionTime:   1499405658657,
+		TradeID:           -1,
+		OrderCreationTime: 1499405658657,
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Test failed. Execution report decoded incorrectly: %+v", report)
+	}
+}
+
+func TestFetchOCOOrder(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binanceOrderListPath || r.URL.Query().Get("orderListId") != "27" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-1102,"msg":"unexpected request"}`)
+			return
+		}
+		fmt.Fprint(w, `{"orderListId":27,"contingencyType":"OCO","listStatusType":"EXEC_STARTED",
+			"listOrderStatus":"EXECUTING","listClientOrderId":"h2USkA5YQpaXHPIrkd96xE",
+			"transactionTime":1565245656253,"symbol":"LTCBTC","orders":[
+			{"symbol":"LTCBTC","orderId":4,"clientOrderId":"qD1gy3kc3Gx0rihm9Y3xwS"},
+			{"symbol":"LTCBTC","orderId":5,"clientOrderId":"ARzZ9I00CPM8i3NhmU9Ega"}]}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	order, err := b.FetchOCOOrder(27, "")
+	if err != nil {
+		t.Fatalf("Test failed. FetchOCOOrder returned error: %s", err)
+	}
+	if order.OrderListID != 27 || len(order.Orders) != 2 || order.Orders[1].OrderID != 5 {
+		t.Errorf("Test failed. FetchOCOOrder decoded incorrectly: %+v", order)
+	}
+}
+
+func TestFetchOpenOCOOrders(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binanceOpenOrderListPath || r.URL.Query().Get("signature") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-1102,"msg":"unexpected request"}`)
+			return
+		}
+		fmt.Fprint(w, `[{"orderListId":31,"contingencyType":"OCO","listStatusType":"EXEC_STARTED",
+			"listOrderStatus":"EXECUTING","listClientOrderId":"wuB13fmulKj3YjdqWEcsnp",
+			"transactionTime":1565246080644,"symbol":"LTCBTC","orders":[
+			{"symbol":"LTCBTC","orderId":4,"clientOrderId":"r3EH2N76dHfLoSZWIUw1bT"},
+			{"symbol":"LTCBTC","orderId":5,"clientOrderId":"Cv1SnyPD3qhqpbjpYEHbd2"}]}]`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	orders, err := b.FetchOpenOCOOrders()
+	if err != nil {
+		t.Fatalf("Test failed. FetchOpenOCOOrders returned error: %s", err)
+	}
+	expected := []OCOOrder{{
+		OrderListID: 31, ContingencyType: "OCO", ListStatusType: "EXEC_STARTED", ListOrderStatus: "EXECUTING",
+		ListClientOrderID: "wuB13fmulKj3YjdqWEcsnp", TransactionTime: 1565246080644, Symbol: "LTCBTC",
+		Orders: []OCOOrderListEntry{
+			{Symbol: "LTCBTC", OrderID: 4, ClientOrderID: "r3EH2N76dHfLoSZWIUw1bT"},
+			{Symbol: "LTCBTC", OrderID: 5, ClientOrderID: "Cv1SnyPD3qhqpbjpYEHbd2"},
+		},
+	}}
+	if !reflect.DeepEqual(orders, expected) {
+		t.Errorf("Test failed. FetchOpenOCOOrders decoded incorrectly: %+v", orders)
+	}
+}
+
+func TestFetchAllCoinsInfo(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binanceAllCoinsInfoPath || r.URL.Query().Get("signature") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-1102,"msg":"unexpected request"}`)
+			return
+		}
+		fmt.Fprint(w, `[{"coin":"BTC","name":"Bitcoin","free":"0.08074558","locked":"0","depositAllEnable":true,
+			"withdrawAllEnable":true,"networkList":[{"network":"BTC","coin":"BTC","name":"BTC",
+			"isDefault":true,"depositEnable":true,"withdrawEnable":false,"withdrawFee":"0.00050000",
+			"withdrawMin":"0.00100000","withdrawMax":"9999999999.99999999","minConfirm":1,
+			"addressRegex":"^[13][a-km-zA-HJ-NP-Z1-9]{25,34}$"}]}]`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	coins, err := b.FetchAllCoinsInfo()
+	if err != nil {
+		t.Fatalf("Test failed. FetchAllCoinsInfo returned error: %s", err)
+	}
+	expected := []CoinInfo{{
+		Coin: "BTC", Name: "Bitcoin", Free: 0.08074558, DepositAllEnable: true, WithdrawAllEnable: true,
+		NetworkList: []CoinNetwork{{
+			Network: "BTC", Coin: "BTC", Name: "BTC", IsDefault: true, DepositEnable: true,
+			WithdrawFee: 0.0005, WithdrawMin: 0.001, WithdrawMax: 9999999999.99999999, MinConfirm: 1,
+			AddressRegex: "^[13][a-km-zA-HJ-NP-Z1-9]{25,34}$",
+		}},
+	}}
+	if !reflect.DeepEqual(coins, expected) {
+		t.Errorf("Test failed. FetchAllCoinsInfo decoded incorrectly: %+v", coins)
+	}
+}
+
+func TestFetchSystemStatus(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binanceSystemStatusPath || r.URL.Query().Get("signature") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-1102,"msg":"unexpected request"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":1,"msg":"system maintenance"}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	status, err := b.FetchSystemStatus()
+	if err != nil {
+		t.Fatalf("Test failed. FetchSystemStatus returned error: %s", err)
+	}
+	if status.Status != SystemStatusMaintenance || status.Message != "system maintenance" {
+		t.Errorf("Test failed. FetchSystemStatus decoded incorrectly: %+v", status)
+	}
+}
+
+func TestAccountInfoBalances(t *testing.T) {
+	t.Parallel()
+	account := AccountInfo{
+		Balances: []*Balance{
+			{Asset: "BTC", Free: 0.1, Locked: 0.2},
+			{Asset: "LTC", Free: 0, Locked: 0},
+			{Asset: "ETH", Free: 0, Locked: 1.5},
+		},
+	}
+
+	if total, ok := account.TotalBalance("btc"); !ok || total != 0.3 {
+		t.Errorf("Test failed. TotalBalance returned %v, %v", total, ok)
+	}
+	if _, ok := account.TotalBalance("XRP"); ok {
+		t.Error("Test failed. TotalBalance found a missing asset")
+	}
+
+	balances := account.NonZeroBalances()
+	if len(balances) != 2 || balances["ETH"].Locked != 1.5 {
+		t.Errorf("Test failed. NonZeroBalances returned %+v", balances)
+	}
+
+	if balances = account.BalanceMap(); len(balances) != 3 || balances["BTC"].Locked != 0.2 {
+		t.Errorf("Test failed. BalanceMap returned %+v", balances)
+	}
+	if free := account.Free("btc"); free != 0.1 {
+		t.Errorf("Test failed. Free returned %v", free)
+	}
+	if free := account.Free("XRP"); free != 0 {
+		t.Errorf("Test failed. Free returned %v for a missing asset", free)
+	}
+}
+
+func TestGetAccountInfo(t *testing.T) {
+	t.Parallel()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"canTrade":true,"balances":[{"asset":"BTC","free":"%d","locked":"0"}]}`, requests)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	info, err := b.GetAccountInfo(time.Minute)
+	if err != nil {
+		t.Fatalf("Test failed. GetAccountInfo returned error: %s", err)
+	}
+	info.Balances[0].Free = 100 // mustn't affect the cached copy
+
+	info, err = b.GetAccountInfo(time.Minute)
+	if err != nil || requests != 1 || info.Balances[0].Free != 1 {
+		t.Errorf("Test failed. Expected cached account info, got %+v, %v after %d requests",
+			info.Balances[0], err, requests)
+	}
+
+	b.accountInfoFetchedAt = b.accountInfoFetchedAt.Add(-time.Minute)
+	b.rateLimits = map[string]int64{}
+	info, err = b.GetAccountInfo(time.Minute)
+	if err != nil || requests != 2 || info.Balances[0].Free != 2 {
+		t.Errorf("Test failed. Expected refreshed account info, got %+v, %v after %d requests",
+			info.Balances[0], err, requests)
+	}
+}
+
+func TestExchangeFilters(t *testing.T) {
+	t.Parallel()
+	b := &Binance{}
+	if _, err := b.ExchangeMaxNumOrders(); err != ErrExchangeInfoNotLoaded {
+		t.Errorf("Test failed. ExchangeMaxNumOrders returned %v before the markets were loaded", err)
+	}
+	if _, err := b.SymbolFilters("BNBBTC"); err != ErrExchangeInfoNotLoaded {
+		t.Errorf("Test failed. SymbolFilters returned %v before the markets were loaded", err)
+	}
+	if _, err := b.SymbolToCurrencyPair("BNBBTC"); err != ErrExchangeInfoNotLoaded {
+		t.Errorf("Test failed. SymbolToCurrencyPair returned %v before the markets were loaded", err)
+	}
+
+	b = newTestMarketsBinance(t)
+	if limit, err := b.ExchangeMaxNumOrders(); err != nil || limit != 1000 {
+		t.Errorf("Test failed. ExchangeMaxNumOrders returned %d, %v", limit, err)
+	}
+	if limit, err := b.ExchangeMaxNumAlgoOrders(); err != nil || limit != 0 {
+		t.Errorf("Test failed. ExchangeMaxNumAlgoOrders returned %d, %v", limit, err)
+	}
+}
+
+func TestSupportsOrderType(t *testing.T) {
+	t.Parallel()
+	b := newTestMarketsBinance(t)
+
+	types, err := b.AllowedOrderTypes("XYZBTC")
+	if err != nil || len(types) != 1 || types[0] != OrderTypeLimit {
+		t.Errorf("Test failed. AllowedOrderTypes returned %v, %v", types, err)
+	}
+
+	if ok, err := b.SupportsOrderType("BNBBTC", OrderTypeMarket); err != nil || !ok {
+		t.Error("Test failed. Expected BNBBTC to support MARKET orders")
+	}
+	if ok, err := b.SupportsOrderType("XYZBTC", OrderTypeMarket); err != nil || ok {
+		t.Error("Test failed. Expected XYZBTC not to support MARKET orders")
+	}
+	if _, err := b.SupportsOrderType("NOPE", OrderTypeMarket); err == nil {
+		t.Error("Test failed. Expected an error for an unknown symbol")
+	}
+}
+
+func TestEstimateMarketBuyQuantity(t *testing.T) {
+	t.Parallel()
+	b := newTestMarketsBinance(t)
+	book := &orderbook.Base{
+		Asks: []orderbook.Item{{Price: 0.003, Amount: 100}},
+	}
+
+	qty, err := b.EstimateMarketBuyQuantity("BNBBTC", 0.1, book)
+	if err != nil || qty != 33.33 {
+		t.Errorf("Test failed. EstimateMarketBuyQuantity returned %v, %v", qty, err)
+	}
+
+	if _, err = b.EstimateMarketBuyQuantity("BNBBTC", 0.1, &orderbook.Base{}); err == nil {
+		t.Error("Test failed. Expected an error for an empty book")
+	}
+}
+
+func TestMaxOrderQuantity(t *testing.T) {
+	t.Parallel()
+	b := newTestMarketsBinance(t)
+	account := &AccountInfo{
+		Balances: []*Balance{{Asset: "BTC", Free: 0.1}, {Asset: "BNB", Free: 12.345}},
+	}
+
+	if qty, err := b.MaxOrderQuantity("BNBBTC", OrderSideBuy, 0.003, account); err != nil || qty != 33.33 {
+		t.Errorf("Test failed. MaxOrderQuantity returned %v, %v for a buy", qty, err)
+	}
+	if qty, err := b.MaxOrderQuantity("BNBBTC", OrderSideSell, 0.003, account); err != nil || qty != 12.34 {
+		t.Errorf("Test failed. MaxOrderQuantity returned %v, %v for a sell", qty, err)
+	}
+	// 0.3 BNB is worth less than the min notional of 0.001 BTC.
+	account.Balances[1].Free = 0.3
+	if _, err := b.MaxOrderQuantity("BNBBTC", OrderSideSell, 0.003, &AccountInfo{Balances: account.Balances}); err == nil {
+		t.Error("Test failed. MaxOrderQuantity should fail below the min notional")
+	}
+	if _, err := b.MaxOrderQuantity("BNBBTC", OrderSideBuy, 0, account); err == nil {
+		t.Error("Test failed. MaxOrderQuantity should fail without a limit price")
+	}
+	if _, err := b.MaxOrderQuantity("BNBBTC", OrderSideBuy, 0.003, nil); err == nil {
+		t.Error("Test failed. MaxOrderQuantity should fail without an account")
+	}
+}
+
+func TestOnRequestHook(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	var calls int
+	b.OnRequest = func(method, path string, status int, latency time.Duration, weightUsed int) {
+		calls++
+		if method != http.MethodGet || path != binanceOpenOrdersPath || status != http.StatusOK ||
+			latency <= 0 || weightUsed != 3 {
+			t.Errorf("Test failed. OnRequest called with %s %s %d %v %d",
+				method, path, status, latency, weightUsed)
+		}
+	}
+	if _, err := b.FetchOpenOrders("BNBBTC"); err != nil {
+		t.Fatalf("Test failed. FetchOpenOrders returned error: %s", err)
+	}
+	if calls != 1 {
+		t.Errorf("Test failed. OnRequest called %d times", calls)
+	}
+}
+
+func TestFetchOpenOrdersSymbol(t *testing.T) {
+	t.Parallel()
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		fmt.Fprint(w, `[{"symbol":"BNBBTC","orderId":1,"status":"NEW"}]`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if _, err := b.FetchOpenOrders("BNBBTC"); err != nil || query.Get("symbol") != "BNBBTC" {
+		t.Errorf("Test failed. FetchOpenOrders sent symbol %q, error %v", query.Get("symbol"), err)
+	}
+	// Skip the request spacing so the second request is sent.
+	b.rateLimits = map[string]int64{}
+	if _, err := b.FetchOpenOrders(""); err != nil || query.Get("symbol") != "" || query.Get("timestamp") == "" {
+		t.Errorf("Test failed. FetchOpenOrders sent symbol %q, error %v", query.Get("symbol"), err)
+	}
+}
+
+func TestExchangeUnavailable(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "<html><body>System maintenance</body></html>")
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	_, err := b.FetchOrder("BNBBTC", 1, "")
+	unavailable, ok := err.(*ErrExchangeUnavailable)
+	if !ok {
+		t.Fatalf("Test failed. Expected ErrExchangeUnavailable, got %v", err)
+	}
+	if unavailable.StatusCode != http.StatusServiceUnavailable || !unavailable.Temporary() ||
+		!strings.Contains(unavailable.Snippet, "System maintenance") {
+		t.Errorf("Test failed. Unexpected error %+v", unavailable)
+	}
+}
+
+func TestSendHTTPRequestRaw(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"code":-1121,"msg":"Invalid symbol."}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	raw, status, err := b.SendHTTPRequestRaw(http.MethodGet, binanceDepthPath,
+		url.Values{"symbol": {"NOPE"}}, RequestSecurityNone)
+	if err != nil {
+		t.Fatalf("Test failed. SendHTTPRequestRaw returned error: %s", err)
+	}
+	if status != http.StatusBadRequest || raw != `{"code":-1121,"msg":"Invalid symbol."}` {
+		t.Errorf("Test failed. Unexpected response %d %s", status, raw)
+	}
+}
+
+func TestSignatureAcrossPathRoots(t *testing.T) {
+	t.Parallel()
+	var mtx sync.Mutex
+	signed := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.RawQuery
+		i := strings.LastIndex(query, "&signature=")
+		mac := hmac.New(sha256.New, []byte("testAPISecret"))
+		mac.Write([]byte(query[:i]))
+		mtx.Lock()
+		signed[r.URL.Path] = i != -1 && query[i+len("&signature="):] == hex.EncodeToString(mac.Sum(nil))
+		mtx.Unlock()
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	for _, path := range []string{binanceAccountPath, binanceMarginAccountPath} {
+		if _, err := b.SendHTTPRequest(http.MethodGet, path, url.Values{"a": {"1"}}, RequestSecuritySign,
+			&struct{}{}); err != nil {
+			t.Fatalf("Test failed. SendHTTPRequest returned error: %s", err)
+		}
+		if !signed["/"+path] {
+			t.Errorf("Test failed. Request to %s wasn't signed correctly", path)
+		}
+	}
+}
+
+func TestRateLimitCooldown(t *testing.T) {
+	t.Parallel()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"code":-1003,"msg":"Too many requests."}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	code, err := b.SendHTTPRequest(http.MethodGet, binanceDepthPath, nil, RequestSecurityNone, &MarketData{})
+	if BinanceErrCode(code) != TooManyRequestsErrCode || err == nil {
+		t.Fatalf("Test failed. Expected -1003 error, got %d %v", code, err)
+	}
+	if _, err = b.FetchOrder("BNBBTC", 1, ""); err != ErrRateLimited {
+		t.Errorf("Test failed. Expected ErrRateLimited, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Test failed. Expected 1 request to be sent, got %d", requests)
+	}
+	if remaining := time.Until(b.cooldownUntil); remaining < 55*time.Second || remaining > 60*time.Second {
+		t.Errorf("Test failed. Unexpected cooldown %v", remaining)
+	}
+}
+
+func TestFetchPrices(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("symbol") == "BNBBTC" {
+			fmt.Fprint(w, `{"symbol":"BNBBTC","price":"0.00245"}`)
+			return
+		}
+		fmt.Fprint(w, `[{"symbol":"BNBBTC","price":"0.00245"},{"symbol":"ETHBTC","price":"0.0315"}]`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	price, err := b.FetchPrice("BNBBTC")
+	if err != nil || price != 0.00245 {
+		t.Errorf("Test failed. FetchPrice returned %v, %v", price, err)
+	}
+	prices, err := b.FetchAllPrices()
+	if err != nil {
+		t.Fatalf("Test failed. FetchAllPrices returned error: %s", err)
+	}
+	if len(prices) != 2 || prices["BNBBTC"] != 0.00245 || prices["ETHBTC"] != 0.0315 {
+		t.Errorf("Test failed. FetchAllPrices returned %v", prices)
+	}
+}
+
+func TestFetch24hTickers(t *testing.T) {
+	t.Parallel()
+	ticker := `{"symbol":"BNBBTC","priceChange":"-0.00001","priceChangePercent":"-0.41","weightedAvgPrice":"0.0024",
+		"prevClosePrice":"0.00246","lastPrice":"0.00245","lastQty":"2.5","bidPrice":"0.00244","bidQty":"10",
+		"askPrice":"0.00245","askQty":"4","openPrice":"0.00246","highPrice":"0.0025","lowPrice":"0.0023",
+		"volume":"150000.5","quoteVolume":"360.25","openTime":1499783499040,"closeTime":1499869899040,
+		"firstId":28385,"lastId":28460,"count":76}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("symbol") == "BNBBTC" {
+			fmt.Fprint(w, ticker)
+			return
+		}
+		fmt.Fprintf(w, "[%s]", ticker)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	t24h, err := b.Fetch24hTicker("BNBBTC")
+	if err != nil {
+		t.Fatalf("Test failed. Fetch24hTicker returned error: %s", err)
+	}
+	if t24h.BaseVolume() != 150000.5 || t24h.QuoteVolume() != 360.25 || t24h.LastPrice != 0.00245 ||
+		t24h.Count != 76 {
+		t.Errorf("Test failed. Fetch24hTicker returned %+v", t24h)
+	}
+	tickers, err := b.FetchAll24hTickers()
+	if err != nil || len(tickers) != 1 || tickers[0].QuoteVolume() != 360.25 {
+		t.Errorf("Test failed. FetchAll24hTickers returned %+v, %v", tickers, err)
+	}
+}
+
+func TestAggregateTrades(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binanceRecentTradesPath || r.FormValue("symbol") != "BNBBTC" ||
+			r.FormValue("limit") != "3" {
+			t.Errorf("Test failed. Unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, `[
+			{"id":1,"price":"0.0020","qty":"10","quoteQty":"0.02","time":1,"isBuyerMaker":false,"isBestMatch":true},
+			{"id":2,"price":"0.0030","qty":"5","quoteQty":"0.015","time":2,"isBuyerMaker":true,"isBestMatch":true},
+			{"id":3,"price":"0.0010","qty":"5","quoteQty":"0.005","time":3,"isBuyerMaker":true,"isBestMatch":true}]`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	trades, err := b.FetchRecentTrades("BNBBTC", 3)
+	if err != nil {
+		t.Fatalf("Test failed. FetchRecentTrades returned error: %s", err)
+	}
+	expected := TradeStats{BuyVolume: 10, SellVolume: 10, VWAP: 0.002, HighPrice: 0.003, LowPrice: 0.001, TradeCount: 3}
+	if stats := AggregateTrades(trades); stats != expected {
+		t.Errorf("Test failed. AggregateTrades returned %+v", stats)
+	}
+	if stats := AggregateTrades(nil); stats != (TradeStats{}) {
+		t.Errorf("Test failed. AggregateTrades returned %+v for no trades", stats)
+	}
+}
+
+func TestDeleteOrderRetry(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			// Simulate a network error by dropping the connection.
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Fatalf("Test failed. Failed to hijack connection: %s", err)
+			}
+			conn.Close()
+		case 2:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-2011,"msg":"Unknown order sent."}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-1121,"msg":"Invalid symbol."}`)
+		}
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if err := b.DeleteOrder("BNBBTC", 1, ""); err != nil {
+		t.Errorf("Test failed. DeleteOrder returned error: %s", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Test failed. DeleteOrder sent %d requests, expected 2", n)
+	}
+	if err := b.DeleteOrder("XXX", 1, ""); err == nil || atomic.LoadInt32(&requests) != 3 {
+		t.Errorf("Test failed. DeleteOrder shouldn't retry an API error, returned %v", err)
+	}
+}
+
+func TestSendHTTPRequestRetry(t *testing.T) {
+	t.Parallel()
+	var requests, dropped int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.AddInt32(&dropped, -1) >= 0 {
+			// Simulate a network error by dropping the connection.
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Fatalf("Test failed. Failed to hijack connection: %s", err)
+			}
+			conn.Close()
+			return
+		}
+		// Otherwise the client transparently retries requests dropped on a reused connection.
+		w.Header().Set("Connection", "close")
+		fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":1,"status":"NEW"}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	b.RequestBackoff = &BackoffPolicy{BaseDelay: time.Millisecond, MaxAttempts: 2}
+	atomic.StoreInt32(&dropped, 2)
+	if order, err := b.FetchOrder("BNBBTC", 1, ""); err != nil || order.OrderID != 1 {
+		t.Errorf("Test failed. FetchOrder returned %+v, error %v", order, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("Test failed. FetchOrder sent %d requests, expected 3", n)
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	atomic.StoreInt32(&dropped, 3)
+	if _, err := b.FetchOrder("BNBBTC", 1, ""); err == nil || atomic.LoadInt32(&requests) != 3 {
+		t.Errorf("Test failed. FetchOrder should give up after the max retries, returned %v", err)
+	}
+
+	// Requests that aren't idempotent aren't retried.
+	atomic.StoreInt32(&requests, 0)
+	atomic.StoreInt32(&dropped, 1)
+	response := PostOrderAckResponse{}
+	if _, err := b.SendHTTPRequest(http.MethodPost, binanceOrderPath, url.Values{}, RequestSecuritySign,
+		&response); err == nil || atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("Test failed. SendHTTPRequest shouldn't retry a POST request, returned %v", err)
+	}
+}
+
+func TestFetchAssetDividends(t *testing.T) {
+	t.Parallel()
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		fmt.Fprint(w, `{"rows":[{"id":242006910,"amount":"10.00000000","asset":"BHFT","divTime":1563189166000,
+			"enInfo":"BHFT distribution","tranId":2968885920}],"total":1}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	start := time.Unix(1560000000, 0)
+	dividends, err := b.FetchAssetDividends("BHFT", start, start.Add(30*24*time.Hour), 100)
+	if err != nil {
+		t.Fatalf("Test failed. FetchAssetDividends returned error: %s", err)
+	}
+	if len(dividends) != 1 || dividends[0].Amount != 10 || dividends[0].TranID != 2968885920 ||
+		dividends[0].EnInfo != "BHFT distribution" {
+		t.Errorf("Test failed. FetchAssetDividends returned %+v", dividends)
+	}
+	if query.Get("asset") != "BHFT" || query.Get("startTime") != "1560000000000" || query.Get("limit") != "100" {
+		t.Errorf("Test failed. Unexpected query %v", query)
+	}
+
+	if _, err = b.FetchAssetDividends("", start, start.Add(91*24*time.Hour), 0); err == nil {
+		t.Error("Test failed. FetchAssetDividends should reject a time range over 90 days")
+	}
+	if _, err = b.FetchAssetDividends("", start, start.Add(-time.Hour), 0); err == nil {
+		t.Error("Test failed. FetchAssetDividends should reject an end time before the start time")
+	}
+}
+
+func TestFetchOrderRateLimitUsage(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binanceOrderRateLimitPath || r.URL.Query().Get("signature") == "" {
+			t.Errorf("Test failed. Unexpected request to %s", r.URL)
+		}
+		fmt.Fprint(w, `[
+			{"rateLimitType":"ORDERS","interval":"SECOND","intervalNum":10,"limit":50,"count":12},
+			{"rateLimitType":"ORDERS","interval":"DAY","intervalNum":1,"limit":160000,"count":1350}]`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	usage, err := b.FetchOrderRateLimitUsage()
+	if err != nil {
+		t.Fatalf("Test failed. FetchOrderRateLimitUsage returned error: %s", err)
+	}
+	expected := []RateLimitUsage{
+		{RateLimit{RateLimitTypeOrders, RateLimitIntervalSecond, 10, 50}, 12},
+		{RateLimit{RateLimitTypeOrders, RateLimitIntervalDay, 1, 160000}, 1350},
+	}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Errorf("Test failed. FetchOrderRateLimitUsage returned %+v", usage)
+	}
+	if usage[0].Window() != 10*time.Second {
+		t.Errorf("Test failed. Unexpected window %s", usage[0].Window())
+	}
+}
+
+func TestConvertQuote(t *testing.T) {
+	t.Parallel()
+	var posted url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		posted = r.PostForm
+		switch r.URL.Path {
+		case "/" + binanceConvertGetQuotePath:
+			fmt.Fprint(w, `{"quoteId":"12415572564","ratio":"38163.7","inverseRatio":"0.0000262","`+
+				`validTimestamp":1623319461670,"toAmount":"3816.37","fromAmount":"0.1"}`)
+		case "/" + binanceConvertAcceptQuotePath:
+			fmt.Fprint(w, `{"orderId":"933256278426274426","createTime":1623381330472,"orderStatus":"PROCESS"}`)
+		default:
+			t.Errorf("Test failed. Unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	quote, err := b.RequestConvertQuote("BTC", "USDT", 0.1)
+	if err != nil {
+		t.Fatalf("Test failed. RequestConvertQuote returned error: %s", err)
+	}
+	if posted.Get("fromAsset") != "BTC" || posted.Get("toAsset") != "USDT" || posted.Get("fromAmount") != "0.1" ||
+		posted.Get("signature") == "" {
+		t.Errorf("Test failed. Unexpected RequestConvertQuote params %v", posted)
+	}
+	expected := ConvertQuote{
+		QuoteID: "12415572564", Ratio: 38163.7, InverseRatio: 0.0000262, ValidTimestamp: 1623319461670,
+		ToAmount: 3816.37, FromAmount: 0.1,
+	}
+	if *quote != expected || !quote.ExpiresAt().Equal(time.Unix(1623319461, 670000000)) {
+		t.Errorf("Test failed. RequestConvertQuote returned %+v", quote)
+	}
+
+	result, err := b.AcceptConvertQuote(quote.QuoteID)
+	if err != nil {
+		t.Fatalf("Test failed. AcceptConvertQuote returned error: %s", err)
+	}
+	if posted.Get("quoteId") != "12415572564" || result.OrderID != "933256278426274426" ||
+		result.CreateTime != 1623381330472 || result.OrderStatus != "PROCESS" {
+		t.Errorf("Test failed. AcceptConvertQuote returned %+v, params %v", result, posted)
+	}
+	if _, err = b.RequestConvertQuote("BTC", "USDT", 0); err == nil {
+		t.Error("Test failed. Expected an error for a zero amount")
+	}
+}
+
+func TestFetchMyAllocations(t *testing.T) {
+	t.Parallel()
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binanceMyAllocationsPath {
+			t.Errorf("Test failed. Unexpected request to %s", r.URL.Path)
+		}
+		query = r.URL.Query()
+		fmt.Fprint(w, `[{"symbol":"BTCUSDT","tradeId":-1,"allocationId":0,"allocationType":"SOR","orderId":1,`+
+			`"orderListId":-1,"price":"1.00000000","qty":"5.00000000","quoteQty":"5.00000000",`+
+			`"commission":"0.00000000","commissionAsset":"BTC","time":1687506878118,"isBuyer":true,`+
+			`"isMaker":false,"isAllocator":false}]`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	start := time.Unix(1687506000, 0)
+	allocations, err := b.FetchMyAllocations("BTCUSDT", start, time.Time{}, 0, 1, 10)
+	if err != nil {
+		t.Fatalf("Test failed. FetchMyAllocations returned error: %s", err)
+	}
+	expected := []Allocation{{
+		Symbol: "BTCUSDT", AllocationType: "SOR", OrderID: 1, OrderListID: -1, Price: 1, Qty: 5,
+		QuoteQty: 5, CommissionAsset: "BTC", Time: 1687506878118, IsBuyer: true,
+	}}
+	if !reflect.DeepEqual(allocations, expected) {
+		t.Errorf("Test failed. FetchMyAllocations returned %+v", allocations)
+	}
+	if query.Get("startTime") != "1687506000000" || query.Get("endTime") != "" || query.Get("orderId") != "1" ||
+		query.Get("fromAllocationId") != "" || query.Get("limit") != "10" || query.Get("signature") == "" {
+		t.Errorf("Test failed. Unexpected FetchMyAllocations params %v", query)
+	}
+	if _, err = b.FetchMyAllocations("", time.Time{}, time.Time{}, 0, 0, 0); err == nil {
+		t.Error("Test failed. Expected an error when no symbol is provided")
+	}
+}
+
+func TestFetchPreventedMatches(t *testing.T) {
+	t.Parallel()
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binancePreventedMatchesPath {
+			t.Errorf("Test failed. Unexpected request to %s", r.URL.Path)
+		}
+		query = r.URL.Query()
+		fmt.Fprint(w, `[{"symbol":"BTCUSDT","preventedMatchId":1,"takerOrderId":5,"makerOrderId":3,`+
+			`"tradeGroupId":1,"selfTradePreventionMode":"EXPIRE_MAKER","price":"1.100000",`+
+			`"makerPreventedQuantity":"1.300000","transactTime":1669101687094}]`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	matches, err := b.FetchPreventedMatches("BTCUSDT", 0, 5, 1, 10)
+	if err != nil {
+		t.Fatalf("Test failed. FetchPreventedMatches returned error: %s", err)
+	}
+	expected := []PreventedMatch{{
+		Symbol: "BTCUSDT", PreventedMatchID: 1, TakerOrderID: 5, MakerOrderID: 3, TradeGroupID: 1,
+		SelfTradePreventionMode: SelfTradePreventionExpireMaker, Price: 1.1, MakerPreventedQuantity: 1.3,
+		TransactTime: 1669101687094,
+	}}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("Test failed. FetchPreventedMatches returned %+v", matches)
+	}
+	if query.Get("orderId") != "5" || query.Get("fromPreventedMatchId") != "1" || query.Get("limit") != "10" ||
+		query.Get("preventedMatchId") != "" || query.Get("signature") == "" {
+		t.Errorf("Test failed. Unexpected FetchPreventedMatches params %v", query)
+	}
+
+	if _, err = b.FetchPreventedMatches("BTCUSDT", 1, 0, 0, 0); err != nil {
+		t.Fatalf("Test failed. FetchPreventedMatches returned error: %s", err)
+	}
+	if query.Get("preventedMatchId") != "1" || query.Get("orderId") != "" {
+		t.Errorf("Test failed. Unexpected FetchPreventedMatches params %v", query)
+	}
+
+	for _, args := range [][3]int64{{0, 0, 0}, {1, 5, 0}} {
+		if _, err = b.FetchPreventedMatches("BTCUSDT", args[0], args[1], args[2], 0); err == nil {
+			t.Errorf("Test failed. Expected an error for FetchPreventedMatches%v", args)
+		}
+	}
+	if _, err = b.FetchPreventedMatches("", 1, 0, 0, 0); err == nil {
+		t.Error("Test failed. Expected an error for a blank symbol")
+	}
+}
+
+func TestBNBBurnStatus(t *testing.T) {
+	t.Parallel()
+	var posted url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binanceBNBBurnPath {
+			t.Errorf("Test failed. Unexpected request to %s", r.URL.Path)
+		}
+		if r.Method == http.MethodPost {
+			r.ParseForm()
+			posted = r.PostForm
+			fmt.Fprintf(w, `{"spotBNBBurn":%s,"interestBNBBurn":%s}`,
+				posted.Get("spotBNBBurn"), posted.Get("interestBNBBurn"))
+			return
+		}
+		fmt.Fprint(w, `{"spotBNBBurn":true,"interestBNBBurn":false}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	spotBurn, interestBurn, err := b.GetBNBBurnStatus()
+	if err != nil || !spotBurn || interestBurn {
+		t.Errorf("Test failed. GetBNBBurnStatus returned %v, %v, %v", spotBurn, interestBurn, err)
+	}
+	if err = b.SetBNBBurnStatus(true, true); err != nil {
+		t.Fatalf("Test failed. SetBNBBurnStatus returned error: %s", err)
+	}
+	if posted.Get("spotBNBBurn") != "true" || posted.Get("interestBNBBurn") != "true" {
+		t.Errorf("Test failed. Unexpected SetBNBBurnStatus params %v", posted)
+	}
+}
+
+func TestParseEnums(t *testing.T) {
+	t.Parallel()
+	for _, orderType := range AllOrderTypes {
+		if parsed, err := ParseOrderType(strings.ToLower(string(orderType))); err != nil || parsed != orderType {
+			t.Errorf("Test failed. ParseOrderType returned %v, %v for %s", parsed, err, orderType)
+		}
+	}
+	if parsed, err := ParseOrderSide("sell"); err != nil || parsed != OrderSideSell {
+		t.Errorf("Test failed. ParseOrderSide returned %v, %v", parsed, err)
+	}
+	if parsed, err := ParseTimeInForce("IOC"); err != nil || parsed != TimeInForceIOC {
+		t.Errorf("Test failed. ParseTimeInForce returned %v, %v", parsed, err)
+	}
+	if _, err := ParseOrderType("STOP"); err == nil {
+		t.Error("Test failed. ParseOrderType should reject an invalid order type")
+	}
+	if _, err := ParseOrderSide(""); err == nil {
+		t.Error("Test failed. ParseOrderSide should reject an empty side")
+	}
+	if _, err := ParseTimeInForce("GTX"); err == nil {
+		t.Error("Test failed. ParseTimeInForce should reject an invalid time in force")
+	}
+}
+
+func TestSyncTime(t *testing.T) {
+	t.Parallel()
+	serverOffset := time.Hour
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"serverTime":%d}`, time.Now().Add(serverOffset).UnixNano()/int64(time.Millisecond))
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if err := b.SyncTime(); err != nil {
+		t.Fatalf("Test failed. SyncTime returned error: %s", err)
+	}
+	if diff := b.ServerTimeOffset() - serverOffset; diff < -time.Second || diff > time.Second {
+		t.Errorf("Test failed. ServerTimeOffset returned %v, expected %v", b.ServerTimeOffset(), serverOffset)
+	}
+}
+
+func TestSignedRequestTimestamp(t *testing.T) {
+	t.Parallel()
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+binanceServerTimePath {
+			fmt.Fprint(w, `{"serverTime":1600000002000}`)
+			return
+		}
+		query = r.URL.RawQuery
+		fmt.Fprint(w, `{"enableReading":true}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	b.nowFunc = func() time.Time { return time.Unix(1600000000, 0) }
+	if err := b.SyncTime(); err != nil {
+		t.Fatalf("Test failed. SyncTime returned error: %s", err)
+	}
+	if b.ServerTimeOffset() != 2*time.Second {
+		t.Errorf("Test failed. ServerTimeOffset returned %v, expected 2s", b.ServerTimeOffset())
+	}
+	if _, err := b.FetchAPIRestrictions(); err != nil {
+		t.Fatalf("Test failed. FetchAPIRestrictions returned error: %s", err)
+	}
+	expected := "timestamp=1600000001000&recvWindow=5000" +
+		"&signature=d184ea2a6b3b927dbb3134721596ac8a4b1640bc3f146dba149420d4d314095d"
+	if query != expected {
+		t.Errorf("Test failed. Unexpected signed payload %s, expected %s", query, expected)
+	}
+}
+
+func TestSymbolFilters(t *testing.T) {
+	t.Parallel()
+	b := newTestMarketsBinance(t)
+
+	filters, err := b.SymbolFilters("BNBBTC")
+	if err != nil {
+		t.Fatalf("Test failed. SymbolFilters returned error: %s", err)
+	}
+	expected := SymbolFilters{
+		TickSize: 0.000001, MinPrice: 0.000001, MaxPrice: 100000,
+		StepSize: 0.01, MinQty: 0.01, MaxQty: 9000000,
+		MinNotional: 0.001,
+	}
+	if *filters != expected {
+		t.Errorf("Test failed. SymbolFilters returned %+v", filters)
+	}
+
+	if _, err = b.SymbolFilters("NOPE"); err == nil {
+		t.Error("Test failed. Expected an error for an unknown symbol")
+	}
+	if _, err = (&Binance{}).SymbolFilters("BNBBTC"); err != ErrExchangeInfoNotLoaded {
+		t.Errorf("Test failed. Expected ErrExchangeInfoNotLoaded, got %v", err)
+	}
+}
+
+func TestSymbolFiltersConcurrentLoadMarkets(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every other exchange info response drops the BNBBTC symbol.
+		if atomic.AddInt32(&requests, 1)%2 == 0 {
+			fmt.Fprint(w, `{"timezone":"UTC","serverTime":1565246363776,"rateLimits":[],"exchangeFilters":[],"symbols":[]}`)
+			return
+		}
+		fmt.Fprint(w, testExchangeInfo)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if err := b.LoadMarkets(); err != nil {
+		t.Fatalf("Test failed. LoadMarkets returned error: %s", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			b.LoadMarkets()
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if filters, err := b.SymbolFilters("BNBBTC"); err == nil && filters.StepSize != 0.01 {
+			t.Fatalf("Test failed. SymbolFilters returned %+v", filters)
+		}
+	}
+}
+
+func TestWaitForOrder(t *testing.T) {
+	t.Parallel()
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binanceOrderPath || r.URL.Query().Get("orderId") != "5" {
+			t.Errorf("Test failed. Unexpected request %s", r.URL)
+		}
+		status := OrderStatusNew
+		if atomic.AddInt32(&fetches, 1) >= 3 {
+			status = OrderStatusFilled
+		}
+		fmt.Fprintf(w, `{"symbol":"BNBBTC","orderId":5,"status":"%s"}`, status)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	order, err := b.WaitForOrder(ctx, "BNBBTC", 5, 10*time.Millisecond)
+	if err != nil || order.Status != OrderStatusFilled || atomic.LoadInt32(&fetches) != 3 {
+		t.Errorf("Test failed. WaitForOrder returned %+v, error %v after %d fetches", order, err,
+			atomic.LoadInt32(&fetches))
+	}
+
+	// The order is only polled every few intervals while the live account shows it's open, so a
+	// fill that the live account missed is still picked up.
+	atomic.StoreInt32(&fetches, 1)
+	b.liveOpenOrders = map[string]map[int64]struct{}{"BNBBTC": {5: {}}}
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	order, err = b.WaitForOrder(ctx, "BNBBTC", 5, 10*time.Millisecond)
+	if err != nil || order.Status != OrderStatusFilled || atomic.LoadInt32(&fetches) != 3 {
+		t.Errorf("Test failed. WaitForOrder returned %+v, error %v after %d fetches", order, err,
+			atomic.LoadInt32(&fetches))
+	}
+
+	atomic.StoreInt32(&fetches, -100)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = b.WaitForOrder(ctx, "BNBBTC", 5, 10*time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("Test failed. Expected WaitForOrder to time out, got %v", err)
+	}
+	if atomic.LoadInt32(&fetches) != -99 {
+		t.Errorf("Test failed. WaitForOrder polled an order that's open in the live account %d times",
+			atomic.LoadInt32(&fetches)+100)
+	}
+	if _, err = b.WaitForOrder(context.Background(), "BNBBTC", 5, 0); err == nil {
+		t.Error("Test failed. Expected an error for a zero poll interval")
+	}
+}
+
+func TestFetchMostRecentOrder(t *testing.T) {
+	t.Parallel()
+	orders := `[{"symbol":"BNBBTC","orderId":7,"clientOrderId":"latest","status":"FILLED"}]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/"+binanceAllOrdersPath || q.Get("symbol") != "BNBBTC" || q.Get("limit") != "1" ||
+			q.Get("orderId") != "" {
+			t.Errorf("Test failed. Unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, orders)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	order, err := b.FetchMostRecentOrder("BNBBTC")
+	if err != nil || order.OrderID != 7 || order.ClientOrderID != "latest" || order.Status != OrderStatusFilled {
+		t.Errorf("Test failed. FetchMostRecentOrder returned %+v, error %v", order, err)
+	}
+	orders = `[]`
+	if _, err = b.FetchMostRecentOrder("BNBBTC"); err != ErrNoOrders {
+		t.Errorf("Test failed. Expected ErrNoOrders, got %v", err)
+	}
+}
+
+func TestFetchAllOrdersPaged(t *testing.T) {
+	t.Parallel()
+	// 1500 orders, one per second, starting with order ID 1 at time 1000s.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		first := int64(1)
+		if q.Get("orderId") != "" {
+			first, _ = strconv.ParseInt(q.Get("orderId"), 10, 64)
+		} else if q.Get("startTime") != "" {
+			startTime, _ := strconv.ParseInt(q.Get("startTime"), 10, 64)
+			first = startTime/1000 - 999
+		}
+		limit, _ := strconv.ParseInt(q.Get("limit"), 10, 64)
+		orders := []string{}
+		for id := first; id <= 1500 && id < first+limit; id++ {
+			status := OrderStatusCanceled
+			if id%3 == 0 {
+				status = OrderStatusFilled
+			}
+			orders = append(orders, fmt.Sprintf(`{"symbol":"BNBBTC","orderId":%d,"time":%d,"status":"%s"}`,
+				id, (id+999)*1000, status))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(orders, ","))
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	orders, err := b.FetchAllOrdersPaged("BNBBTC", time.Unix(1100, 0), time.Unix(2300, 0))
+	if err != nil {
+		t.Fatalf("Test failed. FetchAllOrdersPaged returned error: %s", err)
+	}
+	if len(orders) != 1201 || orders[0].OrderID != 101 || orders[len(orders)-1].OrderID != 1301 {
+		t.Errorf("Test failed. FetchAllOrdersPaged returned %d orders", len(orders))
+	}
+
+	orders, err = b.FetchOrdersByStatus("BNBBTC", OrderStatusFilled, time.Unix(1100, 0), time.Unix(2300, 0))
+	if err != nil {
+		t.Fatalf("Test failed. FetchOrdersByStatus returned error: %s", err)
+	}
+	if len(orders) != 400 || orders[0].OrderID != 102 || orders[len(orders)-1].OrderID != 1299 {
+		t.Errorf("Test failed. FetchOrdersByStatus returned %d orders", len(orders))
+	}
+
+	// A zero end time is treated as the current time.
+	b.nowFunc = func() time.Time { return time.Unix(2300, 0) }
+	orders, err = b.FetchAllOrdersPaged("BNBBTC", time.Unix(1100, 0), time.Time{})
+	if err != nil || len(orders) != 1201 {
+		t.Errorf("Test failed. FetchAllOrdersPaged returned %d orders, error %v", len(orders), err)
+	}
+	for _, tc := range [][2]time.Time{
+		{{}, time.Unix(2300, 0)},
+		{time.Unix(2300, 0), time.Unix(1100, 0)},
+	} {
+		if _, err = b.FetchAllOrdersPaged("BNBBTC", tc[0], tc[1]); err == nil {
+			t.Errorf("Test failed. Expected an error for time range %v - %v", tc[0], tc[1])
+		}
+		if _, err = b.FetchOrdersByStatus("BNBBTC", OrderStatusFilled, tc[0], tc[1]); err == nil {
+			t.Errorf("Test failed. Expected an error for time range %v - %v", tc[0], tc[1])
+		}
+	}
+}
+
+func TestClientOrderIDPrefix(t *testing.T) {
+	t.Parallel()
+	var posted url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		posted = r.PostForm
+		fmt.Fprintf(w, `{"symbol":"BNBBTC","orderId":1,"clientOrderId":"%s"}`, posted.Get("newClientOrderId"))
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	params := &PostOrderParams{Symbol: "BNBBTC", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 1}
+	if _, err := b.PostOrderAck(params); err != nil {
+		t.Fatalf("Test failed. PostOrderAck returned error: %s", err)
+	}
+	if posted.Get("newClientOrderId") != "" {
+		t.Errorf("Test failed. Unexpected client order ID %s without a prefix", posted.Get("newClientOrderId"))
+	}
+
+	b.ClientOrderIDPrefix = "strat1-"
+	if _, err := b.PostOrderAck(params); err != nil {
+		t.Fatalf("Test failed. PostOrderAck returned error: %s", err)
+	}
+	id := posted.Get("newClientOrderId")
+	if !strings.HasPrefix(id, "strat1-") || id != params.NewClientOrderID {
+		t.Errorf("Test failed. Unexpected generated client order ID %s", id)
+	}
+	if b.GenerateClientOrderID() == b.GenerateClientOrderID() {
+		t.Error("Test failed. GenerateClientOrderID returned duplicate IDs")
+	}
+
+	b.ClientOrderIDPrefix = strings.Repeat("x", 40)
+	if id = b.GenerateClientOrderID(); len(id) > 36 || !strings.HasPrefix(id, "xxxx") {
+		t.Errorf("Test failed. GenerateClientOrderID returned %s for a long prefix", id)
+	}
+}
+
+func TestSelfTradePreventionMode(t *testing.T) {
+	t.Parallel()
+	var posted url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+binanceExchangeInfoPath {
+			fmt.Fprint(w, testExchangeInfo)
+			return
+		}
+		r.ParseForm()
+		posted = r.PostForm
+		fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":1}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if err := b.LoadMarkets(); err != nil {
+		t.Fatalf("Test failed. LoadMarkets returned error: %s", err)
+	}
+	if info, _ := b.getSymbolInfo("BNBBTC"); len(info.AllowedSelfTradePreventionModes) != 3 ||
+		info.DefaultSelfTradePreventionMode != SelfTradePreventionNone {
+		t.Errorf("Test failed. Unexpected self-trade prevention modes in symbol info %+v", info)
+	}
+	params := &PostOrderParams{Symbol: "BNBBTC", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 1}
+	if _, err := b.PostOrderAck(params); err != nil {
+		t.Fatalf("Test failed. PostOrderAck returned error: %s", err)
+	}
+	if _, ok := posted["selfTradePreventionMode"]; ok {
+		t.Error("Test failed. Self-trade prevention mode sent when it wasn't set")
+	}
+
+	params.SelfTradePreventionMode = SelfTradePreventionExpireBoth
+	if _, err := b.PostOrderFull(params); err != nil {
+		t.Fatalf("Test failed. PostOrderFull returned error: %s", err)
+	}
+	if posted.Get("selfTradePreventionMode") != "EXPIRE_BOTH" {
+		t.Errorf("Test failed. Unexpected self-trade prevention mode %s", posted.Get("selfTradePreventionMode"))
+	}
+
+	posted = nil
+	for _, mode := range []SelfTradePreventionMode{"EXPIRE_ALL", SelfTradePreventionExpireMaker} {
+		params.SelfTradePreventionMode = mode
+		if _, err := b.PostOrderAck(params); err == nil {
+			t.Errorf("Test failed. Expected an error for self-trade prevention mode %s", mode)
+		}
+	}
+	if posted != nil {
+		t.Error("Test failed. An order with an invalid self-trade prevention mode was sent")
+	}
+	// XYZBTC doesn't restrict the modes.
+	params.Symbol = "XYZBTC"
+	if _, err := b.PostOrderAck(params); err != nil {
+		t.Errorf("Test failed. PostOrderAck returned error: %s", err)
+	}
+}
+
+func TestPostOrder(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.PostForm.Get("newOrderRespType") {
+		case "ACK":
+			fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":28,"clientOrderId":"abc","transactTime":1507725176595}`)
+		case "RESULT":
+			fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":28,"clientOrderId":"abc","transactTime":1507725176595,
+				"price":"0.00000000","origQty":"10.00000000","executedQty":"10.00000000",
+				"cummulativeQuoteQty":"10.00000000","status":"FILLED","timeInForce":"GTC","type":"MARKET",
+				"side":"SELL"}`)
+		case "FULL":
+			fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":28,"clientOrderId":"abc","transactTime":1507725176595,
+				"price":"0.00000000","origQty":"10.00000000","executedQty":"10.00000000",
+				"cummulativeQuoteQty":"10.00000000","status":"FILLED","timeInForce":"GTC","type":"MARKET",
+				"side":"SELL","fills":[{"price":"1.00000000","qty":"10.00000000","commission":"0.01000000",
+				"commissionAsset":"BNB","tradeId":56}]}`)
+		default:
+			t.Errorf("Test failed. Unexpected response type %s", r.PostForm.Get("newOrderRespType"))
+		}
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	params := &PostOrderParams{Symbol: "BNBBTC", Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 10}
+	response, err := b.PostOrder(params)
+	if err != nil || response.OrderID != 28 || response.HasResult() || response.HasFills() {
+		t.Errorf("Test failed. PostOrder returned %+v, error %v", response, err)
+	}
+	b.DefaultOrderRespType = OrderRespTypeResult
+	response, err = b.PostOrder(params)
+	if err != nil || response.Status != OrderStatusFilled || !response.HasResult() || response.HasFills() {
+		t.Errorf("Test failed. PostOrder returned %+v, error %v", response, err)
+	}
+	b.DefaultOrderRespType = OrderRespTypeFull
+	response, err = b.PostOrder(params)
+	if err != nil || !response.HasResult() || !response.HasFills() || len(response.Fills) != 1 ||
+		response.Fills[0].TradeID != 56 {
+		t.Errorf("Test failed. PostOrder returned %+v, error %v", response, err)
+	}
+	b.DefaultOrderRespType = "PARTIAL"
+	if _, err = b.PostOrder(params); err == nil {
+		t.Error("Test failed. Expected an error for an invalid order response type")
+	}
+}
+
+func TestQuoteOrderQty(t *testing.T) {
+	t.Parallel()
+	var posted url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+binanceExchangeInfoPath {
+			fmt.Fprint(w, testExchangeInfo)
+			return
+		}
+		r.ParseForm()
+		posted = r.PostForm
+		fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":1}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if _, err := b.QuoteOrderQtyMarketAllowed("BNBBTC"); err != ErrExchangeInfoNotLoaded {
+		t.Errorf("Test failed. Expected ErrExchangeInfoNotLoaded, got %v", err)
+	}
+	// Without the symbol info the quote order quantity is sent as is.
+	params := &PostOrderParams{Symbol: "BNBBTC", Side: OrderSideBuy, Type: OrderTypeMarket, QuoteOrderQty: 0.123456789}
+	if _, err := b.PostOrderAck(params); err != nil {
+		t.Fatalf("Test failed. PostOrderAck returned error: %s", err)
+	}
+	if posted.Get("quoteOrderQty") != "0.123456789" {
+		t.Errorf("Test failed. Unexpected quote order quantity %s", posted.Get("quoteOrderQty"))
+	}
+
+	if err := b.LoadMarkets(); err != nil {
+		t.Fatalf("Test failed. LoadMarkets returned error: %s", err)
+	}
+	for symbol, expected := range map[string]bool{"BNBBTC": true, "XYZBTC": false} {
+		if allowed, err := b.QuoteOrderQtyMarketAllowed(symbol); err != nil || allowed != expected {
+			t.Errorf("Test failed. QuoteOrderQtyMarketAllowed(%s) returned %v, error %v", symbol, allowed, err)
+		}
+	}
+	if qty, err := b.RoundQuoteOrderQty("BNBBTC", 0.123456789); err != nil || qty != 0.12345678 {
+		t.Errorf("Test failed. RoundQuoteOrderQty returned %v, error %v", qty, err)
+	}
+
+	params.QuoteOrderQty = 0.123456789
+	if _, err := b.PostOrderFull(params); err != nil {
+		t.Fatalf("Test failed. PostOrderFull returned error: %s", err)
+	}
+	if posted.Get("quoteOrderQty") != "0.12345678" {
+		t.Errorf("Test failed. Unexpected quote order quantity %s", posted.Get("quoteOrderQty"))
+	}
+	posted = nil
+	params.Symbol = "XYZBTC"
+	if _, err := b.PostOrderAck(params); err == nil || posted != nil {
+		t.Error("Test failed. Expected an error for a symbol that doesn't allow quote order quantities")
+	}
+}
+
+func TestPostOrders(t *testing.T) {
+	t.Parallel()
+	placed := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("price") == "0.5" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-1013,"msg":"Filter failure: PRICE_FILTER"}`)
+			return
+		}
+		placed++
+		fmt.Fprintf(w, `{"symbol":"BNBBTC","orderId":%d,"clientOrderId":"%s"}`, placed,
+			r.PostForm.Get("newClientOrderId"))
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	b.orderLimits = []RateLimit{{RateLimitTypeOrders, RateLimitIntervalSecond, 10, 3}}
+	ladder := func(price float64, id string) *PostOrderParams {
+		return &PostOrderParams{
+			Symbol: "BNBBTC", Side: OrderSideBuy, Type: OrderTypeLimit, TimeInForce: TimeInForceGTC,
+			Quantity: 1, Price: price, NewClientOrderID: id,
+		}
+	}
+	results, err := b.PostOrders([]*PostOrderParams{
+		ladder(0.1, "a"), ladder(0.5, "b"), nil, ladder(0.2, "c"), ladder(0.3, "d"),
+	})
+	if err == nil || err.Error() != "failed to place 3 of 5 orders" || len(results) != 5 {
+		t.Fatalf("Test failed. PostOrders returned %v, error %v", results, err)
+	}
+	for i, expectedID := range []string{"a", "", "", "c", ""} {
+		result := results[i]
+		if expectedID != "" && (result.Err != nil || result.Order.ClientOrderID != expectedID) {
+			t.Errorf("Test failed. Unexpected result %d: %+v", i, result)
+		}
+		if expectedID == "" && (result.Err == nil || result.Order != nil) {
+			t.Errorf("Test failed. Expected result %d to fail, got %+v", i, result)
+		}
+	}
+	if results[1].Err.Error() != "Filter failure: PRICE_FILTER" || results[4].Err != ErrTooManyOrders {
+		t.Errorf("Test failed. Unexpected errors %v, %v", results[1].Err, results[4].Err)
+	}
+
+	b.orderTimes = nil
+	if results, err = b.PostOrders([]*PostOrderParams{ladder(0.1, "e")}); err != nil || results[0].Order.OrderID != 3 {
+		t.Errorf("Test failed. PostOrders returned %v, error %v", results, err)
+	}
+}
+
+func TestTrailingDelta(t *testing.T) {
+	t.Parallel()
+	var posted url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		posted = r.PostForm
+		fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":1}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	params := &PostOrderParams{
+		Symbol: "BNBBTC", Side: OrderSideSell, Type: OrderTypeStopLossLimit, TimeInForce: TimeInForceGTC,
+		Quantity: 1, Price: 0.001, TrailingDelta: 200,
+	}
+	if _, err := b.PostOrderAck(params); err != nil {
+		t.Fatalf("Test failed. PostOrderAck returned error: %s", err)
+	}
+	if posted.Get("trailingDelta") != "200" {
+		t.Errorf("Test failed. Unexpected trailing delta %s", posted.Get("trailingDelta"))
+	}
+
+	params.TrailingDelta = 0
+	if _, err := b.PostOrderAck(params); err != nil {
+		t.Fatalf("Test failed. PostOrderAck returned error: %s", err)
+	}
+	if _, ok := posted["trailingDelta"]; ok {
+		t.Error("Test failed. Trailing delta sent when it wasn't set")
+	}
+
+	posted = nil
+	for _, p := range []PostOrderParams{
+		{Symbol: "BNBBTC", Side: OrderSideSell, Type: OrderTypeLimit, Quantity: 1, Price: 1, TrailingDelta: 100},
+		{Symbol: "BNBBTC", Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 1, TrailingDelta: 100},
+		{Symbol: "BNBBTC", Side: OrderSideSell, Type: OrderTypeTakeProfit, Quantity: 1, TrailingDelta: -100},
+	} {
+		if _, err := b.PostOrderFull(&p); err == nil {
+			t.Errorf("Test failed. Expected an error for a %s order with trailing delta %d", p.Type,
+				p.TrailingDelta)
+		}
+	}
+	if posted != nil {
+		t.Error("Test failed. An order with an invalid trailing delta was sent")
+	}
+}
+
+func TestStrategyParams(t *testing.T) {
+	t.Parallel()
+	var posted url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		posted = r.PostForm
+		fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":1}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	params := &PostOrderParams{
+		Symbol: "BNBBTC", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 1, StrategyID: 37,
+		StrategyType: 1000200,
+	}
+	if _, err := b.PostOrderAck(params); err != nil {
+		t.Fatalf("Test failed. PostOrderAck returned error: %s", err)
+	}
+	if posted.Get("strategyId") != "37" || posted.Get("strategyType") != "1000200" {
+		t.Errorf("Test failed. Unexpected strategy params %v", posted)
+	}
+
+	params.StrategyID, params.StrategyType = 0, 0
+	if _, err := b.PostOrderAck(params); err != nil {
+		t.Fatalf("Test failed. PostOrderAck returned error: %s", err)
+	}
+	if _, ok := posted["strategyId"]; ok {
+		t.Error("Test failed. Strategy ID sent when it wasn't set")
+	}
+	if _, ok := posted["strategyType"]; ok {
+		t.Error("Test failed. Strategy type sent when it wasn't set")
+	}
+
+	posted = nil
+	params.StrategyType = 999999
+	if _, err := b.PostOrderFull(params); err == nil {
+		t.Error("Test failed. Expected an error for a strategy type below 1000000")
+	}
+	if posted != nil {
+		t.Error("Test failed. An order with an invalid strategy type was sent")
+	}
+}
+
+func TestPlaceOrderIdempotent(t *testing.T) {
+	t.Parallel()
+	placed := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("origClientOrderId") == "existing" {
+				fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":1,"clientOrderId":"existing","time":1000}`)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-2013,"msg":"Order does not exist."}`)
+		case http.MethodPost:
+			placed++
+			fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":2,"clientOrderId":"new","transactTime":2000}`)
+		}
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	params := &PostOrderParams{Symbol: "BNBBTC", Side: OrderSideBuy, Type: OrderTypeLimit,
+		TimeInForce: TimeInForceGTC, Quantity: 1, Price: 0.001, NewClientOrderID: "existing"}
+	resp, err := b.PlaceOrderIdempotent(params)
+	if err != nil || resp.OrderID != 1 || placed != 0 {
+		t.Errorf("Test failed. Expected the existing order to be returned, got %+v, %v", resp, err)
+	}
+
+	params.NewClientOrderID = "new"
+	resp, err = b.PlaceOrderIdempotent(params)
+	if err != nil || resp.OrderID != 2 || placed != 1 {
+		t.Errorf("Test failed. Expected a new order to be placed, got %+v, %v", resp, err)
+	}
+
+	params.NewClientOrderID = ""
+	if _, err = b.PlaceOrderIdempotent(params); err == nil {
+		t.Error("Test failed. Expected an error without a client order ID")
+	}
+}
+
+func TestFetchExchangeInfoForSymbols(t *testing.T) {
+	t.Parallel()
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		fmt.Fprint(w, testExchangeInfo)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	info, err := b.FetchExchangeInfoForSymbols([]string{"BNBBTC", "XYZBTC"})
+	if err != nil {
+		t.Fatalf("Test failed. FetchExchangeInfoForSymbols returned error: %s", err)
+	}
+	if query.Get("symbols") != `["BNBBTC","XYZBTC"]` || len(info.Symbols) != 2 {
+		t.Errorf("Test failed. Unexpected symbols param %s", query.Get("symbols"))
+	}
+
+	if _, err = b.FetchExchangeInfoForSymbols([]string{"BNBBTC"}); err != nil || query.Get("symbol") != "BNBBTC" {
+		t.Errorf("Test failed. Unexpected symbol param %s", query.Get("symbol"))
+	}
+}
+
+func TestTotalCommissionByAsset(t *testing.T) {
+	t.Parallel()
+	data := `{"symbol":"BTCUSDT","orderId":28,"status":"FILLED","type":"MARKET","side":"SELL","fills":[
+		{"price":"4000.00000000","qty":"1.00000000","commission":"4.00000000","commissionAsset":"USDT","tradeId":56},
+		{"price":"3999.00000000","qty":"5.00000000","commission":"0.10000000","commissionAsset":"BNB","tradeId":57},
+		{"price":"3998.00000000","qty":"2.00000000","commission":"0.20000000","commissionAsset":"BNB","tradeId":58}]}`
+	response := PostOrderFullResponse{}
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		t.Fatalf("Test failed. Failed to decode response: %s", err)
+	}
+	totals := response.TotalCommissionByAsset()
+	if len(totals) != 2 || totals["USDT"] != 4 || totals["BNB"] != 0.3 {
+		t.Errorf("Test failed. PostOrderFullResponse.TotalCommissionByAsset returned %v", totals)
+	}
+
+	trades := []AccountTrade{
+		{Commission: 0.1, CommissionAsset: "BNB"},
+		{Commission: 0.2, CommissionAsset: "BNB"},
+	}
+	if totals = TotalCommissionByAsset(trades); len(totals) != 1 || totals["BNB"] != 0.3 {
+		t.Errorf("Test failed. TotalCommissionByAsset returned %v", totals)
+	}
+}
+
+func TestAnnotateFills(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("orderId") != "28" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `[{"symbol":"BTCUSDT","id":56,"orderId":28,"isMaker":true},
+			{"symbol":"BTCUSDT","id":57,"orderId":28,"isMaker":false}]`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	response := &PostOrderFullResponse{
+		Symbol:  "BTCUSDT",
+		OrderID: 28,
+		Fills:   []OrderFill{{TradeID: 56, Qty: 1}, {TradeID: 57, Qty: 2}},
+	}
+	fills, err := b.AnnotateFills(response)
+	if err != nil {
+		t.Fatalf("Test failed. AnnotateFills returned error: %s", err)
+	}
+	if len(fills) != 2 || !fills[0].IsMaker || fills[0].Qty != 1 || fills[1].IsMaker || fills[1].Qty != 2 {
+		t.Errorf("Test failed. AnnotateFills returned %+v", fills)
+	}
+
+	response.Fills = append(response.Fills, OrderFill{TradeID: 58})
+	if _, err = b.AnnotateFills(response); err == nil {
+		t.Error("Test failed. AnnotateFills should fail if a fill has no matching trade")
+	}
+}
+
+func TestVerifyPermissions(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ipRestrict":false,"createTime":1623840271000,"enableWithdrawals":false,
+			"enableInternalTransfer":true,"permitsUniversalTransfer":true,"enableVanillaOptions":false,
+			"enableReading":true,"enableFutures":false,"enableMargin":false,"enableSpotAndMarginTrading":true}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if err := b.VerifyPermissions(PermissionReading, PermissionSpotAndMarginTrading); err != nil {
+		t.Errorf("Test failed. VerifyPermissions returned error: %s", err)
+	}
+	err := b.VerifyPermissions(PermissionReading, PermissionWithdrawals, PermissionMargin)
+	if err == nil || !strings.Contains(err.Error(), PermissionWithdrawals+", "+PermissionMargin) {
+		t.Errorf("Test failed. VerifyPermissions returned unexpected error: %v", err)
+	}
+}
+
+func TestFetchAPIKeyIPRestriction(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+binanceIPRestrictionPath || r.URL.Query().Get("signature") == "" {
+			t.Errorf("Test failed. Unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, `{"ipRestrict":"true","ipList":["69.210.67.14","8.34.21.10"],
+			"updateTime":1636371437000,"apiKey":"testkey"}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	restriction, err := b.FetchAPIKeyIPRestriction()
+	if err != nil {
+		t.Fatalf("Test failed. FetchAPIKeyIPRestriction returned error: %s", err)
+	}
+	if !restriction.IPRestrict || !reflect.DeepEqual(restriction.IPList, []string{"69.210.67.14", "8.34.21.10"}) ||
+		restriction.UpdateTime != 1636371437000 {
+		t.Errorf("Test failed. FetchAPIKeyIPRestriction returned %+v", restriction)
+	}
+}
+
+func TestSplitOrder(t *testing.T) {
+	t.Parallel()
+	b := newTestMarketsBinance(t)
+
+	// BNBBTC has a step size of 0.01 and a min notional of 0.001.
+	quantities, err := b.SplitOrder("BNBBTC", 10.057, 4, 0.01)
+	if err != nil {
+		t.Fatalf("Test failed. SplitOrder returned error: %s", err)
+	}
+	if !reflect.DeepEqual(quantities, []float64{2.52, 2.51, 2.51, 2.51}) {
+		t.Errorf("Test failed. SplitOrder returned %v", quantities)
+	}
+	if quantities, err = b.SplitOrder("BNBBTC", 1, 1, 0.01); err != nil || !reflect.DeepEqual(quantities, []float64{1}) {
+		t.Errorf("Test failed. SplitOrder returned %v, error %v", quantities, err)
+	}
+
+	for _, tc := range []struct {
+		totalQty float64
+		slices   int
+		price    float64
+	}{
+		{1, 10, 0.001},   // slices below min notional
+		{0.05, 10, 1},    // slices smaller than the step size
+		{1, 0, 0.01},     // no slices
+		{0, 1, 0.01},     // no quantity
+		{0.001, 1, 0.01}, // quantity below step size
+	} {
+		if quantities, err = b.SplitOrder("BNBBTC", tc.totalQty, tc.slices, tc.price); err == nil {
+			t.Errorf("Test failed. Expected SplitOrder(%v, %d, %v) to fail, got %v", tc.totalQty, tc.slices,
+				tc.price, quantities)
+		}
+	}
+	if _, err = b.SplitOrder("ABCXYZ", 1, 1, 1); err == nil {
+		t.Error("Test failed. Expected an error for an unknown symbol")
+	}
+}
+
+func TestFormatQuoteBase(t *testing.T) {
+	t.Parallel()
+	b := newTestMarketsBinance(t)
+	for _, tc := range []struct {
+		value    float64
+		expected string
+	}{
+		{0.00001, "0.00001000"},
+		{0.000000001, "0.00000000"},
+		{0.000000005, "0.00000001"},
+		{1234567.123456789, "1234567.12345679"},
+		{-2.5, "-2.50000000"},
+		{0, "0.00000000"},
+	} {
+		if s, err := b.FormatQuote("BNBBTC", tc.value); err != nil || s != tc.expected {
+			t.Errorf("Test failed. FormatQuote(%v) returned %q, error %v, expected %q", tc.value, s, err,
+				tc.expected)
+		}
+		if s, err := b.FormatBase("BNBBTC", tc.value); err != nil || s != tc.expected {
+			t.Errorf("Test failed. FormatBase(%v) returned %q, error %v, expected %q", tc.value, s, err,
+				tc.expected)
+		}
+	}
+	if _, err := b.FormatQuote("ABCXYZ", 1); err == nil {
+		t.Error("Test failed. Expected an error for an unknown symbol")
+	}
+	if _, err := (&Binance{}).FormatBase("BNBBTC", 1); err != ErrExchangeInfoNotLoaded {
+		t.Errorf("Test failed. Expected ErrExchangeInfoNotLoaded, got %v", err)
+	}
+}
+
+func TestMinNotional(t *testing.T) {
+	t.Parallel()
+	if _, err := (&Binance{}).MinNotional("BNBBTC"); err != ErrExchangeInfoNotLoaded {
+		t.Errorf("Test failed. Expected ErrExchangeInfoNotLoaded, got %v", err)
+	}
+	b := newTestMarketsBinance(t)
+	// BNBBTC has a legacy MIN_NOTIONAL filter, XYZBTC a NOTIONAL filter.
+	for symbol, expected := range map[string]float64{"BNBBTC": 0.001, "XYZBTC": 0.0001} {
+		if minNotional, err := b.MinNotional(symbol); err != nil || minNotional != expected {
+			t.Errorf("Test failed. MinNotional(%s) returned %v, error %v", symbol, minNotional, err)
+		}
+	}
+	if _, err := b.MinNotional("ABCXYZ"); err == nil {
+		t.Error("Test failed. Expected an error for an unknown symbol")
+	}
+}
+
+func TestSymbolPermissions(t *testing.T) {
+	t.Parallel()
+	b := newTestMarketsBinance(t)
+	permissions, err := b.SymbolPermissions("BNBBTC")
+	if err != nil || !reflect.DeepEqual(permissions, []string{SymbolPermissionSpot, SymbolPermissionMargin}) {
+		t.Errorf("Test failed. SymbolPermissions returned %v, error %v", permissions, err)
+	}
+	for symbol, expected := range map[string]bool{"BNBBTC": true, "XYZBTC": false} {
+		if spot, err := b.SymbolSupportsSpot(symbol); err != nil || spot != expected {
+			t.Errorf("Test failed. SymbolSupportsSpot(%s) returned %v, error %v", symbol, spot, err)
+		}
+	}
+	if _, err = b.SymbolSupportsSpot("ABCXYZ"); err == nil {
+		t.Error("Test failed. Expected an error for an unknown symbol")
+	}
+}
+
+func TestValidateSymbol(t *testing.T) {
+	t.Parallel()
+	if err := (&Binance{}).ValidateSymbol("BNBBTC"); err != ErrExchangeInfoNotLoaded {
+		t.Errorf("Test failed. Expected ErrExchangeInfoNotLoaded, got %v", err)
+	}
+	b := newTestMarketsBinance(t)
+	for symbol, expected := range map[string]string{
+		"BNBBTC": "",
+		"XYZBTC": "symbol 'XYZBTC' is not trading (status BREAK)",
+		"bnbbtc": "unknown symbol 'bnbbtc', did you mean 'BNBBTC'?",
+		"BNBUSD": "unknown symbol 'BNBUSD', did you mean 'BNBBTC'?",
+		"XYZETH": "unknown symbol 'XYZETH', did you mean 'XYZBTC'?",
+		"ETHUSD": "unknown symbol 'ETHUSD'",
+	} {
+		err := b.ValidateSymbol(symbol)
+		if (expected == "" && err != nil) || (expected != "" && (err == nil || err.Error() != expected)) {
+			t.Errorf("Test failed. ValidateSymbol(%s) returned %v, expected %q", symbol, err, expected)
+		}
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	t.Parallel()
+	var mtx sync.Mutex
+	requested := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		requested[r.URL.Path] = true
+		mtx.Unlock()
+		switch r.URL.Path {
+		case "/" + binancePingPath:
+			fmt.Fprint(w, `{}`)
+		case "/" + binanceServerTimePath:
+			fmt.Fprintf(w, `{"serverTime":%d}`, time.Now().UnixNano()/int64(time.Millisecond))
+		case "/" + binanceExchangeInfoPath:
+			fmt.Fprint(w, testExchangeInfo)
+		case "/" + binanceAPIRestrictionsPath:
+			fmt.Fprint(w, `{"enableReading":true,"enableSpotAndMarginTrading":false}`)
+		default:
+			t.Errorf("Test failed. Unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	b.EnabledPairs = []string{"BNBBTC"}
+	if err := b.Warmup(); err != nil {
+		t.Fatalf("Test failed. Warmup returned error: %s", err)
+	}
+	mtx.Lock()
+	if len(requested) != 4 {
+		t.Errorf("Test failed. Warmup sent requests to %v", requested)
+	}
+	mtx.Unlock()
+	if _, err := b.SymbolFilters("BNBBTC"); err != nil {
+		t.Errorf("Test failed. Warmup didn't load the markets: %s", err)
+	}
+
+	err := b.Warmup(PermissionSpotAndMarginTrading)
+	if err == nil || !strings.Contains(err.Error(), "verify permissions") {
+		t.Errorf("Test failed. Warmup returned unexpected error: %v", err)
+	}
+
+	b.EnabledPairs = []string{"BNBBTC", "BNBUSD"}
+	err = b.Warmup()
+	if err == nil || !strings.Contains(err.Error(), "'BNBUSD', did you mean 'BNBBTC'?") {
+		t.Errorf("Test failed. Warmup returned unexpected error: %v", err)
+	}
+}
+
+func TestFetchMarketDataLimit(t *testing.T) {
+	t.Parallel()
+	var query url.Values
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		path = r.URL.Path
+		fmt.Fprint(w, `{"lastUpdateId":1027024,"bids":[["4.00000000","431.00000000"]],"asks":[["4.00000200","12.00000000"]]}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	data, err := b.FetchMarketData("BNBBTC", 5000)
+	if err != nil {
+		t.Fatalf("Test failed. FetchMarketData returned error: %s", err)
+	}
+	if path != "/"+binanceDepthPath || query.Get("limit") != "5000" || len(data.Bids) != 1 ||
+		data.Asks[0].Quantity != 12 {
+		t.Errorf("Test failed. FetchMarketData requested %s?%v", path, query)
+	}
+
+	// Unsupported limits are rounded up, zero & negative limits use the default.
+	for limit, expected := range map[int64]string{200: "500", 1: "5", 100: "100", 9999: "5000", 0: "", -1: ""} {
+		b.rateLimits = map[string]int64{}
+		if _, err = b.FetchMarketData("BNBBTC", limit); err != nil {
+			t.Fatalf("Test failed. FetchMarketData returned error: %s", err)
+		}
+		if query.Get("limit") != expected {
+			t.Errorf("Test failed. FetchMarketData(%d) requested limit %q, expected %q", limit,
+				query.Get("limit"), expected)
+		}
+	}
+}
+
+func TestAvailableBuyingPower(t *testing.T) {
+	t.Parallel()
+	tradeEnabled := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + binanceMarginAccountPath:
+			fmt.Fprintf(w, `{"borrowEnabled":true,"marginLevel":"11.64405625","tradeEnabled":%t,
+				"transferEnabled":true,"userAssets":[{"asset":"BTC","borrowed":"0.50000000",
+				"free":"1.00000000","interest":"0.00000000","locked":"0.00000000","netAsset":"0.50000000"}]}`,
+				tradeEnabled)
+		case "/" + binanceMaxBorrowablePath:
+			fmt.Fprint(w, `{"amount":"1.69248805","borrowLimit":"60"}`)
+		}
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if power, err := b.AvailableBuyingPower("BTC"); err != nil || power != 2.19248805 {
+		t.Errorf("Test failed. AvailableBuyingPower returned %v, %v", power, err)
+	}
+
+	tradeEnabled = false
+	if _, err := b.AvailableBuyingPower("BTC"); err == nil {
+		t.Error("Test failed. Expected an error when margin trading is disabled")
+	}
+}
+
+func TestFetchCombinedBalances(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + binanceAccountPath:
+			fmt.Fprint(w, `{"canTrade":true,"balances":[{"asset":"BTC","free":"0.1","locked":"0.2"},
+				{"asset":"ETH","free":"3","locked":"0"}]}`)
+		case "/" + binanceMarginAccountPath:
+			fmt.Fprint(w, `{"borrowEnabled":true,"tradeEnabled":true,"userAssets":[{"asset":"BTC",
+				"borrowed":"0.5","free":"1","interest":"0.01","locked":"0","netAsset":"0.49"},
+				{"asset":"USDT","borrowed":"0","free":"100","interest":"0","locked":"0","netAsset":"100"}]}`)
+		}
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	balances, err := b.FetchCombinedBalances()
+	if err != nil {
+		t.Fatalf("Test failed. FetchCombinedBalances returned error: %s", err)
+	}
+	expected := map[string]CombinedBalance{
+		"BTC":  {Asset: "BTC", Free: 1.1, Locked: 0.2, Borrowed: 0.5, Net: 0.79},
+		"ETH":  {Asset: "ETH", Free: 3, Net: 3},
+		"USDT": {Asset: "USDT", Free: 100, Net: 100},
+	}
+	if !reflect.DeepEqual(balances, expected) {
+		t.Errorf("Test failed. FetchCombinedBalances returned %+v", balances)
+	}
+}
+
+func TestDeleteAllOpenOrdersEverywhere(t *testing.T) {
+	t.Parallel()
+	var mtx sync.Mutex
+	var cancelled []string
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&fetches, 1)
+			fmt.Fprint(w, `[{"symbol":"BNBBTC","orderId":1},{"symbol":"ETHBTC","orderId":2},
+				{"symbol":"BNBBTC","orderId":3},{"symbol":"LTCBTC","orderId":4}]`)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		v, _ := url.ParseQuery(string(body))
+		symbol := v.Get("symbol")
+		mtx.Lock()
+		cancelled = append(cancelled, symbol)
+		mtx.Unlock()
+		if symbol == "LTCBTC" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-2011,"msg":"Unknown order sent."}`)
+			return
+		}
+		fmt.Fprintf(w, `[{"symbol":"%s","orderId":1,"origClientOrderId":"a","clientOrderId":"b"}]`, symbol)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	result, err := b.DeleteAllOpenOrdersEverywhere()
+	if err == nil || !strings.Contains(err.Error(), "LTCBTC") {
+		t.Errorf("Test failed. Expected error listing LTCBTC, got %v", err)
+	}
+	if !reflect.DeepEqual(cancelled, []string{"BNBBTC", "ETHBTC", "LTCBTC"}) {
+		t.Errorf("Test failed. Unexpected symbols cancelled %v", cancelled)
+	}
+	if len(result) != 2 || result[0].Symbol != "BNBBTC" || result[1].Symbol != "ETHBTC" {
+		t.Errorf("Test failed. Unexpected result %+v", result)
+	}
+	if atomic.LoadInt32(&fetches) != 1 {
+		t.Errorf("Test failed. First call fetched open orders %d times", atomic.LoadInt32(&fetches))
+	}
+
+	// A second call straight after the first must fetch the open orders again rather than acting on
+	// a throttled (cached) set, even if FetchOpenOrders was also just called.
+	if _, err = b.FetchOpenOrders(""); err != nil {
+		t.Fatalf("Test failed. FetchOpenOrders returned error: %s", err)
+	}
+	mtx.Lock()
+	cancelled = nil
+	mtx.Unlock()
+	atomic.StoreInt32(&fetches, 0)
+	if _, err = b.DeleteAllOpenOrdersEverywhere(); err == nil || !strings.Contains(err.Error(), "LTCBTC") {
+		t.Errorf("Test failed. Expected error listing LTCBTC, got %v", err)
+	}
+	if atomic.LoadInt32(&fetches) != 1 || !reflect.DeepEqual(cancelled, []string{"BNBBTC", "ETHBTC", "LTCBTC"}) {
+		t.Errorf("Test failed. Second call fetched open orders %d times and cancelled %v",
+			atomic.LoadInt32(&fetches), cancelled)
+	}
+}
+
+func TestRemainingOrders(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+binanceExchangeInfoPath {
+			fmt.Fprint(w, `{"rateLimits":[
+				{"rateLimitType":"REQUEST_WEIGHT","interval":"MINUTE","intervalNum":1,"limit":1200},
+				{"rateLimitType":"ORDERS","interval":"SECOND","intervalNum":10,"limit":2},
+				{"rateLimitType":"ORDERS","interval":"DAY","intervalNum":1,"limit":200000}],"symbols":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"symbol":"BNBBTC","orderId":1}`)
+	}))
+	defer server.Close()
+
+	b := newTestBinance(server)
+	if remaining := b.RemainingOrders(); remaining != -1 {
+		t.Errorf("Test failed. Expected unknown remaining orders, got %d", remaining)
+	}
+	if err := b.LoadMarkets(); err != nil {
+		t.Fatalf("Test failed. LoadMarkets returned error: %s", err)
+	}
+	if remaining := b.RemainingOrders(); remaining != 2 {
+		t.Errorf("Test failed. Expected 2 remaining orders, got %d", remaining)
+	}
+
+	params := &PostOrderParams{Symbol: "BNBBTC", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 1}
+	if _, err := b.PostOrderAck(params); err != nil {
+		t.Fatalf("Test failed. PostOrderAck returned error: %s", err)
+	}
+	if _, err := b.PostOrderFull(params); err != nil {
+		t.Fatalf("Test failed. PostOrderFull returned error: %s", err)
+	}
+	if remaining := b.RemainingOrders(); remaining != 0 {
+		t.Errorf("Test failed. Expected 0 remaining orders, got %d", remaining)
+	}
+	if _, err := b.PostOrderAck(params); err != ErrTooManyOrders {
+		t.Errorf("Test failed. Expected ErrTooManyOrders, got %v", err)
+	}
+
+	// Orders placed more than 10 seconds ago only count towards the daily limit.
+	b.orderTimes[0] = b.orderTimes[0].Add(-time.Minute)
+	b.orderTimes[1] = b.orderTimes[1].Add(-time.Minute)
+	if remaining := b.RemainingOrders(); remaining != 2 {
+		t.Errorf("Test failed. Expected 2 remaining orders, got %d", remaining)
+	}
+}
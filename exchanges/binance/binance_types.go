@@ -2,7 +2,10 @@ package binance
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -26,7 +29,102 @@ type AccountInfo struct {
 	CanTrade         bool       `json:"canTrade"`
 	CanWithdraw      bool       `json:"canWithdraw"`
 	CanDeposit       bool       `json:"canDeposit"`
+	UpdateTime       int64      `json:"updateTime"` // msecs since the Unix epoch
 	Balances         []*Balance `json:"balances"`
+	// Built from Balances on the first call to BalanceMap.
+	balanceMap map[string]Balance
+}
+
+// TotalBalance returns the total (free + locked) balance of the given asset, the asset is
+// matched case-insensitively. Returns false if the account has no balance for the asset.
+func (a *AccountInfo) TotalBalance(asset string) (float64, bool) {
+	for _, balance := range a.Balances {
+		if strings.EqualFold(balance.Asset, asset) {
+			total, _ := decimal.NewFromFloat(balance.Free).Add(decimal.NewFromFloat(balance.Locked)).Float64()
+			return total, true
+		}
+	}
+	return 0, false
+}
+
+// NonZeroBalances returns all the balances that have a non-zero free or locked amount, keyed by
+// asset.
+func (a *AccountInfo) NonZeroBalances() map[string]Balance {
+	balances := map[string]Balance{}
+	for _, balance := range a.Balances {
+		if balance.Free != 0 || balance.Locked != 0 {
+			balances[balance.Asset] = *balance
+		}
+	}
+	return balances
+}
+
+// BalanceMap returns all the balances keyed by asset. The map is built on the first call and
+// reused by subsequent calls, so it must not be modified, and it won't reflect any changes made
+// to Balances after the first call. Not safe for concurrent use.
+func (a *AccountInfo) BalanceMap() map[string]Balance {
+	if a.balanceMap == nil {
+		a.balanceMap = make(map[string]Balance, len(a.Balances))
+		for _, balance := range a.Balances {
+			a.balanceMap[balance.Asset] = *balance
+		}
+	}
+	return a.balanceMap
+}
+
+// Free returns the free balance of the given asset, or zero if the account has no balance for the
+// asset. The asset is matched case-insensitively.
+func (a *AccountInfo) Free(asset string) float64 {
+	return a.BalanceMap()[strings.ToUpper(asset)].Free
+}
+
+// clone returns a deep copy of the account info.
+func (a *AccountInfo) clone() *AccountInfo {
+	c := *a
+	c.balanceMap = nil
+	c.Balances = make([]*Balance, len(a.Balances))
+	for i, balance := range a.Balances {
+		b := *balance
+		c.Balances[i] = &b
+	}
+	return &c
+}
+
+// MarginAsset is the state of an asset on the cross margin account.
+type MarginAsset struct {
+	Asset    string  `json:"asset"`
+	Borrowed float64 `json:"borrowed,string"`
+	Free     float64 `json:"free,string"`
+	Interest float64 `json:"interest,string"`
+	Locked   float64 `json:"locked,string"`
+	NetAsset float64 `json:"netAsset,string"`
+}
+
+// MarginAccount is the state of the cross margin account.
+type MarginAccount struct {
+	BorrowEnabled       bool          `json:"borrowEnabled"`
+	MarginLevel         float64       `json:"marginLevel,string"`
+	TotalAssetOfBtc     float64       `json:"totalAssetOfBtc,string"`
+	TotalLiabilityOfBtc float64       `json:"totalLiabilityOfBtc,string"`
+	TotalNetAssetOfBtc  float64       `json:"totalNetAssetOfBtc,string"`
+	TradeEnabled        bool          `json:"tradeEnabled"`
+	TransferEnabled     bool          `json:"transferEnabled"`
+	UserAssets          []MarginAsset `json:"userAssets"`
+}
+
+type bnbBurnStatus struct {
+	SpotBNBBurn     bool `json:"spotBNBBurn"`
+	InterestBNBBurn bool `json:"interestBNBBurn"`
+}
+
+// CombinedBalance is the balance of an asset across the spot & cross margin accounts.
+type CombinedBalance struct {
+	Asset    string
+	Free     float64
+	Locked   float64
+	Borrowed float64
+	// Free + locked - borrowed - interest
+	Net float64
 }
 
 type OrderType string
@@ -41,6 +139,23 @@ const (
 	OrderTypeLimitMaker      OrderType = "LIMIT_MAKER"
 )
 
+// AllOrderTypes contains every valid OrderType.
+var AllOrderTypes = []OrderType{
+	OrderTypeMarket, OrderTypeLimit, OrderTypeStopLoss, OrderTypeStopLossLimit, OrderTypeTakeProfit,
+	OrderTypeTakeProfitLimit, OrderTypeLimitMaker,
+}
+
+// ParseOrderType converts the given string to an OrderType, the string is matched
+// case-insensitively.
+func ParseOrderType(s string) (OrderType, error) {
+	for _, t := range AllOrderTypes {
+		if strings.EqualFold(string(t), s) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid order type '%s'", s)
+}
+
 type OrderStatus string
 
 const (
@@ -60,6 +175,20 @@ const (
 	OrderSideSell OrderSide = "SELL"
 )
 
+// AllOrderSides contains every valid OrderSide.
+var AllOrderSides = []OrderSide{OrderSideBuy, OrderSideSell}
+
+// ParseOrderSide converts the given string to an OrderSide, the string is matched
+// case-insensitively.
+func ParseOrderSide(s string) (OrderSide, error) {
+	for _, side := range AllOrderSides {
+		if strings.EqualFold(string(side), s) {
+			return side, nil
+		}
+	}
+	return "", fmt.Errorf("invalid order side '%s'", s)
+}
+
 type TimeInForce string
 
 const (
@@ -68,6 +197,54 @@ const (
 	TimeInForceFOK TimeInForce = "FOK" // Fill or Kill
 )
 
+// AllTimeInForce contains every valid TimeInForce.
+var AllTimeInForce = []TimeInForce{TimeInForceGTC, TimeInForceIOC, TimeInForceFOK}
+
+// ParseTimeInForce converts the given string to a TimeInForce, the string is matched
+// case-insensitively.
+func ParseTimeInForce(s string) (TimeInForce, error) {
+	for _, tif := range AllTimeInForce {
+		if strings.EqualFold(string(tif), s) {
+			return tif, nil
+		}
+	}
+	return "", fmt.Errorf("invalid time in force '%s'", s)
+}
+
+// SelfTradePreventionMode determines which order(s) expire when an order would trade against
+// another order of the same account.
+type SelfTradePreventionMode string
+
+const (
+	SelfTradePreventionNone        SelfTradePreventionMode = "NONE"
+	SelfTradePreventionExpireTaker SelfTradePreventionMode = "EXPIRE_TAKER"
+	SelfTradePreventionExpireMaker SelfTradePreventionMode = "EXPIRE_MAKER"
+	SelfTradePreventionExpireBoth  SelfTradePreventionMode = "EXPIRE_BOTH"
+)
+
+// OrderRespType determines which order details are returned when an order is placed.
+type OrderRespType string
+
+const (
+	// Only the order IDs and transaction time.
+	OrderRespTypeAck OrderRespType = "ACK"
+	// The order details, excluding the fills.
+	OrderRespTypeResult OrderRespType = "RESULT"
+	// The order details, including the fills.
+	OrderRespTypeFull OrderRespType = "FULL"
+)
+
+// AllOrderRespTypes contains every valid OrderRespType.
+var AllOrderRespTypes = []OrderRespType{OrderRespTypeAck, OrderRespTypeResult, OrderRespTypeFull}
+
+// AllSelfTradePreventionModes contains every valid SelfTradePreventionMode.
+var AllSelfTradePreventionModes = []SelfTradePreventionMode{
+	SelfTradePreventionNone, SelfTradePreventionExpireTaker, SelfTradePreventionExpireMaker,
+	SelfTradePreventionExpireBoth,
+}
+
+// Order is an order fetched from the exchange, the type, side and time in force are decoded into
+// the same types used by PostOrderParams so they can be passed back to the exchange as is.
 type Order struct {
 	Symbol        string      `json:"symbol"`
 	OrderID       int64       `json:"orderId"`
@@ -85,8 +262,252 @@ type Order struct {
 	IsWorking     bool        `json:"isWorking"`
 }
 
+// IsTerminal returns true if the order is in a final state (i.e. filled, canceled, rejected, or
+// expired), so its status won't change anymore.
+func (o *Order) IsTerminal() bool {
+	switch o.Status {
+	case OrderStatusFilled, OrderStatusCanceled, OrderStatusRejected, OrderStatusExpired:
+		return true
+	}
+	return false
+}
+
+// OCOOrderListEntry identifies one of the orders in an OCO order list.
+type OCOOrderListEntry struct {
+	Symbol        string `json:"symbol"`
+	OrderID       int64  `json:"orderId"`
+	ClientOrderID string `json:"clientOrderId"`
+}
+
+// OCOOrder is an OCO (one-cancels-the-other) order list fetched from the exchange.
+type OCOOrder struct {
+	OrderListID       int64               `json:"orderListId"`
+	ContingencyType   string              `json:"contingencyType"`
+	ListStatusType    string              `json:"listStatusType"`
+	ListOrderStatus   string              `json:"listOrderStatus"`
+	ListClientOrderID string              `json:"listClientOrderId"`
+	TransactionTime   int64               `json:"transactionTime"`
+	Symbol            string              `json:"symbol"`
+	Orders            []OCOOrderListEntry `json:"orders"`
+}
+
+// CoinNetwork describes a network a coin can be deposited to or withdrawn from.
+type CoinNetwork struct {
+	Network        string  `json:"network"`
+	Coin           string  `json:"coin"`
+	Name           string  `json:"name"`
+	IsDefault      bool    `json:"isDefault"`
+	DepositEnable  bool    `json:"depositEnable"`
+	WithdrawEnable bool    `json:"withdrawEnable"`
+	WithdrawFee    float64 `json:"withdrawFee,string"`
+	WithdrawMin    float64 `json:"withdrawMin,string"`
+	WithdrawMax    float64 `json:"withdrawMax,string"`
+	MinConfirm     int     `json:"minConfirm"`
+	AddressRegex   string  `json:"addressRegex"`
+}
+
+// CoinInfo describes a coin that can be deposited to or withdrawn from the account.
+type CoinInfo struct {
+	Coin              string        `json:"coin"`
+	Name              string        `json:"name"`
+	Free              float64       `json:"free,string"`
+	Locked            float64       `json:"locked,string"`
+	DepositAllEnable  bool          `json:"depositAllEnable"`
+	WithdrawAllEnable bool          `json:"withdrawAllEnable"`
+	NetworkList       []CoinNetwork `json:"networkList"`
+}
+
+// AssetDividend is a record of an asset distribution (e.g. an airdrop or dividend) credited to the
+// account.
+type AssetDividend struct {
+	ID     int64   `json:"id"`
+	Amount float64 `json:"amount,string"`
+	Asset  string  `json:"asset"`
+	// Time of the distribution in milliseconds since the Unix epoch.
+	DivTime int64 `json:"divTime"`
+	// Description of the distribution.
+	EnInfo string `json:"enInfo"`
+	TranID int64  `json:"tranId"`
+}
+
+// ConvertQuote is a quote for converting one asset to another via Binance Convert.
+type ConvertQuote struct {
+	QuoteID string `json:"quoteId"`
+	// Amount of the to asset received per unit of the from asset, and vice versa.
+	Ratio        float64 `json:"ratio,string"`
+	InverseRatio float64 `json:"inverseRatio,string"`
+	// Time the quote expires in milliseconds since the Unix epoch.
+	ValidTimestamp int64   `json:"validTimestamp"`
+	FromAmount     float64 `json:"fromAmount,string"`
+	ToAmount       float64 `json:"toAmount,string"`
+}
+
+// ExpiresAt returns the time the quote expires.
+func (q *ConvertQuote) ExpiresAt() time.Time {
+	return time.Unix(0, q.ValidTimestamp*int64(time.Millisecond))
+}
+
+// ConvertResult is the result of accepting a ConvertQuote.
+type ConvertResult struct {
+	OrderID    string `json:"orderId"`
+	CreateTime int64  `json:"createTime"`
+	// One of PROCESS, ACCEPT_SUCCESS, SUCCESS, or FAIL.
+	OrderStatus string `json:"orderStatus"`
+}
+
+// API key permissions, as named in the API key restrictions.
+const (
+	PermissionReading              = "enableReading"
+	PermissionSpotAndMarginTrading = "enableSpotAndMarginTrading"
+	PermissionWithdrawals          = "enableWithdrawals"
+	PermissionInternalTransfer     = "enableInternalTransfer"
+	PermissionMargin               = "enableMargin"
+	PermissionFutures              = "enableFutures"
+	PermissionVanillaOptions       = "enableVanillaOptions"
+	PermissionUniversalTransfer    = "permitsUniversalTransfer"
+)
+
+// APIRestrictions describes the permissions granted to an API key.
+type APIRestrictions struct {
+	IPRestrict                     bool  `json:"ipRestrict"`
+	CreateTime                     int64 `json:"createTime"`
+	EnableReading                  bool  `json:"enableReading"`
+	EnableSpotAndMarginTrading     bool  `json:"enableSpotAndMarginTrading"`
+	EnableWithdrawals              bool  `json:"enableWithdrawals"`
+	EnableInternalTransfer         bool  `json:"enableInternalTransfer"`
+	EnableMargin                   bool  `json:"enableMargin"`
+	EnableFutures                  bool  `json:"enableFutures"`
+	EnableVanillaOptions           bool  `json:"enableVanillaOptions"`
+	PermitsUniversalTransfer       bool  `json:"permitsUniversalTransfer"`
+	TradingAuthorityExpirationTime int64 `json:"tradingAuthorityExpirationTime"`
+}
+
+// Permissions returns the API key permissions mapped to whether they're enabled or not.
+func (r *APIRestrictions) Permissions() map[string]bool {
+	return map[string]bool{
+		PermissionReading:              r.EnableReading,
+		PermissionSpotAndMarginTrading: r.EnableSpotAndMarginTrading,
+		PermissionWithdrawals:          r.EnableWithdrawals,
+		PermissionInternalTransfer:     r.EnableInternalTransfer,
+		PermissionMargin:               r.EnableMargin,
+		PermissionFutures:              r.EnableFutures,
+		PermissionVanillaOptions:       r.EnableVanillaOptions,
+		PermissionUniversalTransfer:    r.PermitsUniversalTransfer,
+	}
+}
+
+// IPRestriction describes the IP addresses an API key is restricted to.
+type IPRestriction struct {
+	IPRestrict bool     `json:"ipRestrict,string"`
+	IPList     []string `json:"ipList"`
+	UpdateTime int64    `json:"updateTime"`
+	APIKey     string   `json:"apiKey"`
+}
+
+type SystemStatusCode int
+
+const (
+	SystemStatusNormal      SystemStatusCode = 0
+	SystemStatusMaintenance SystemStatusCode = 1
+)
+
+type SystemStatus struct {
+	Status  SystemStatusCode `json:"status"`
+	Message string           `json:"msg"`
+}
+
+// TickerPrice is the last price of a symbol.
+type TickerPrice struct {
+	Symbol string  `json:"symbol"`
+	Price  float64 `json:"price,string"`
+}
+
+// Ticker24h is the 24 hour rolling window price change statistics of a symbol.
+type Ticker24h struct {
+	Symbol             string  `json:"symbol"`
+	PriceChange        float64 `json:"priceChange,string"`
+	PriceChangePercent float64 `json:"priceChangePercent,string"`
+	WeightedAvgPrice   float64 `json:"weightedAvgPrice,string"`
+	PrevClosePrice     float64 `json:"prevClosePrice,string"`
+	LastPrice          float64 `json:"lastPrice,string"`
+	LastQty            float64 `json:"lastQty,string"`
+	BidPrice           float64 `json:"bidPrice,string"`
+	BidQty             float64 `json:"bidQty,string"`
+	AskPrice           float64 `json:"askPrice,string"`
+	AskQty             float64 `json:"askQty,string"`
+	OpenPrice          float64 `json:"openPrice,string"`
+	HighPrice          float64 `json:"highPrice,string"`
+	LowPrice           float64 `json:"lowPrice,string"`
+	// Volume traded in the base asset.
+	Volume float64 `json:"volume,string"`
+	// Volume traded in the quote asset, see QuoteVolume.
+	QuoteAssetVolume float64 `json:"quoteVolume,string"`
+	OpenTime         int64   `json:"openTime"`
+	CloseTime        int64   `json:"closeTime"`
+	FirstID          int64   `json:"firstId"`
+	LastID           int64   `json:"lastId"`
+	Count            int64   `json:"count"`
+}
+
+// BaseVolume returns the volume traded in the base asset.
+func (t *Ticker24h) BaseVolume() float64 {
+	return t.Volume
+}
+
+// QuoteVolume returns the volume traded in the quote asset.
+func (t *Ticker24h) QuoteVolume() float64 {
+	return t.QuoteAssetVolume
+}
+
+type RateLimitType string
+
+const (
+	RateLimitTypeRequestWeight RateLimitType = "REQUEST_WEIGHT"
+	RateLimitTypeOrders        RateLimitType = "ORDERS"
+	RateLimitTypeRawRequests   RateLimitType = "RAW_REQUESTS"
+)
+
+type RateLimitInterval string
+
+const (
+	RateLimitIntervalSecond RateLimitInterval = "SECOND"
+	RateLimitIntervalMinute RateLimitInterval = "MINUTE"
+	RateLimitIntervalDay    RateLimitInterval = "DAY"
+)
+
+// RateLimit is a limit on the number of requests (or orders) allowed within a time window.
+type RateLimit struct {
+	RateLimitType RateLimitType     `json:"rateLimitType"`
+	Interval      RateLimitInterval `json:"interval"`
+	IntervalNum   int               `json:"intervalNum"`
+	Limit         int               `json:"limit"`
+}
+
+// Window returns the length of the time window the limit applies to, or zero if the interval is
+// unknown.
+func (r *RateLimit) Window() time.Duration {
+	switch r.Interval {
+	case RateLimitIntervalSecond:
+		return time.Duration(r.IntervalNum) * time.Second
+	case RateLimitIntervalMinute:
+		return time.Duration(r.IntervalNum) * time.Minute
+	case RateLimitIntervalDay:
+		return time.Duration(r.IntervalNum) * 24 * time.Hour
+	}
+	return 0
+}
+
+// RateLimitUsage is the current usage of a rate limit.
+type RateLimitUsage struct {
+	RateLimit
+	// Usage within the current interval.
+	Count int `json:"count"`
+}
+
 type ExchangeInfo struct {
-	Symbols []SymbolInfo
+	RateLimits      []RateLimit      `json:"rateLimits"`
+	ExchangeFilters []ExchangeFilter `json:"exchangeFilters"`
+	Symbols         []SymbolInfo
 }
 
 type SymbolStatus string
@@ -101,8 +522,24 @@ const (
 	FilterTypePrice       FilterType = "PRICE_FILTER"
 	FilterTypeLotSize     FilterType = "LOT_SIZE"
 	FilterTypeMinNotional FilterType = "MIN_NOTIONAL"
+	// Supersedes MIN_NOTIONAL, both filters have the same min notional parameter.
+	FilterTypeNotional FilterType = "NOTIONAL"
+
+	FilterTypeExchangeMaxNumOrders     FilterType = "EXCHANGE_MAX_NUM_ORDERS"
+	FilterTypeExchangeMaxNumAlgoOrders FilterType = "EXCHANGE_MAX_NUM_ALGO_ORDERS"
 )
 
+// ExchangeFilter is an account-wide limit that applies across all symbols.
+type ExchangeFilter struct {
+	Type FilterType `json:"filterType"`
+
+	// EXCHANGE_MAX_NUM_ORDERS parameters
+	MaxNumOrders int `json:"maxNumOrders"`
+
+	// EXCHANGE_MAX_NUM_ALGO_ORDERS parameters
+	MaxNumAlgoOrders int `json:"maxNumAlgoOrders"`
+}
+
 type SymbolInfoFilter struct {
 	Type FilterType `json:"filterType"`
 
@@ -116,10 +553,51 @@ type SymbolInfoFilter struct {
 	MaxQty   decimal.Decimal `json:"maxQty,string"`
 	StepSize decimal.Decimal `json:"stepSize,string"`
 
-	// MIN_NOTIONAL parameters
+	// MIN_NOTIONAL & NOTIONAL parameters
 	MinNotional decimal.Decimal `json:"minNotional,string"`
 }
 
+// SymbolFilters contains the values of the commonly used symbol filters, values of filters that
+// aren't defined for the symbol are set to zero.
+type SymbolFilters struct {
+	// PRICE_FILTER
+	TickSize float64
+	MinPrice float64
+	MaxPrice float64
+	// LOT_SIZE
+	StepSize float64
+	MinQty   float64
+	MaxQty   float64
+	// MIN_NOTIONAL
+	MinNotional float64
+}
+
+func newSymbolFilters(info *SymbolInfo) *SymbolFilters {
+	sf := &SymbolFilters{}
+	for _, filter := range info.Filters {
+		switch filter.Type {
+		case FilterTypePrice:
+			sf.TickSize, _ = filter.TickSize.Float64()
+			sf.MinPrice, _ = filter.MinPrice.Float64()
+			sf.MaxPrice, _ = filter.MaxPrice.Float64()
+		case FilterTypeLotSize:
+			sf.StepSize, _ = filter.StepSize.Float64()
+			sf.MinQty, _ = filter.MinQty.Float64()
+			sf.MaxQty, _ = filter.MaxQty.Float64()
+		case FilterTypeMinNotional, FilterTypeNotional:
+			sf.MinNotional, _ = filter.MinNotional.Float64()
+		}
+	}
+	return sf
+}
+
+// Symbol permissions, as named in the exchange info.
+const (
+	SymbolPermissionSpot      = "SPOT"
+	SymbolPermissionMargin    = "MARGIN"
+	SymbolPermissionLeveraged = "LEVERAGED"
+)
+
 type SymbolInfo struct {
 	Symbol              string             `json:"symbol"`
 	Status              SymbolStatus       `json:"status"`
@@ -130,6 +608,13 @@ type SymbolInfo struct {
 	OrderTypes          []OrderType        `json:"orderTypes"`
 	Iceberg             bool               `json:"icebergAllowed"`
 	Filters             []SymbolInfoFilter `json:"filters"`
+	// Whether MARKET orders can specify the quote order quantity instead of the quantity.
+	QuoteOrderQtyMarketAllowed bool `json:"quoteOrderQtyMarketAllowed"`
+	// Account permissions that allow trading the symbol, e.g. SPOT or MARGIN.
+	Permissions []string `json:"permissions"`
+	// Self-trade prevention modes that can be used for orders, and the mode used by default.
+	AllowedSelfTradePreventionModes []SelfTradePreventionMode `json:"allowedSelfTradePreventionModes"`
+	DefaultSelfTradePreventionMode  SelfTradePreventionMode   `json:"defaultSelfTradePreventionMode"`
 }
 
 type PostOrderAckResponse struct {
@@ -139,6 +624,190 @@ type PostOrderAckResponse struct {
 	TransactTime  int64  `json:"transactTime"`
 }
 
+// OrderFill is one of the trades that filled an order.
+type OrderFill struct {
+	TradeID         int64   `json:"tradeId"`
+	Price           float64 `json:"price,string"`
+	Qty             float64 `json:"qty,string"`
+	Commission      float64 `json:"commission,string"`
+	CommissionAsset string  `json:"commissionAsset"`
+}
+
+// AnnotatedFill is an order fill along with whether the order was the maker in the fill.
+type AnnotatedFill struct {
+	OrderFill
+	IsMaker bool
+}
+
+type PostOrderFullResponse struct {
+	Symbol              string      `json:"symbol"`
+	OrderID             int64       `json:"orderId"`
+	ClientOrderID       string      `json:"clientOrderId"`
+	TransactTime        int64       `json:"transactTime"`
+	Price               float64     `json:"price,string"`
+	OrigQty             float64     `json:"origQty,string"`
+	ExecutedQty         float64     `json:"executedQty,string"`
+	CummulativeQuoteQty float64     `json:"cummulativeQuoteQty,string"`
+	Status              OrderStatus `json:"status"`
+	TimeInForce         TimeInForce `json:"timeInForce"`
+	Type                OrderType   `json:"type"`
+	Side                OrderSide   `json:"side"`
+	// The fills that occurred while the order was being placed. The response doesn't say whether
+	// the order was the maker or the taker in each fill, use Binance.AnnotateFills to find out.
+	Fills []OrderFill `json:"fills"`
+}
+
+// TotalCommissionByAsset returns the total commission charged for all the order fills, keyed by
+// the asset the commission was charged in.
+func (r *PostOrderFullResponse) TotalCommissionByAsset() map[string]float64 {
+	totals := map[string]decimal.Decimal{}
+	for _, fill := range r.Fills {
+		totals[fill.CommissionAsset] = totals[fill.CommissionAsset].Add(decimal.NewFromFloat(fill.Commission))
+	}
+	return commissionTotalsToFloats(totals)
+}
+
+// PostOrderResponse is returned by PostOrder, the fields that are populated depend on the
+// response type the order was placed with: only the order IDs and TransactTime for ACK, all the
+// fields except Fills for RESULT, and all the fields for FULL.
+type PostOrderResponse struct {
+	PostOrderFullResponse
+	RespType OrderRespType `json:"-"`
+}
+
+// HasResult returns true if the response includes the order details (status, quantities, etc).
+func (r *PostOrderResponse) HasResult() bool {
+	return r.RespType == OrderRespTypeResult || r.RespType == OrderRespTypeFull
+}
+
+// HasFills returns true if the response includes the fills that occurred while the order was being
+// placed (which may still be empty if the order wasn't filled immediately).
+func (r *PostOrderResponse) HasFills() bool {
+	return r.RespType == OrderRespTypeFull
+}
+
+// AccountTrade is a trade made by the account.
+type AccountTrade struct {
+	Symbol          string  `json:"symbol"`
+	ID              int64   `json:"id"`
+	OrderID         int64   `json:"orderId"`
+	OrderListID     int64   `json:"orderListId"`
+	Price           float64 `json:"price,string"`
+	Qty             float64 `json:"qty,string"`
+	QuoteQty        float64 `json:"quoteQty,string"`
+	Commission      float64 `json:"commission,string"`
+	CommissionAsset string  `json:"commissionAsset"`
+	Time            int64   `json:"time"`
+	IsBuyer         bool    `json:"isBuyer"`
+	IsMaker         bool    `json:"isMaker"`
+	IsBestMatch     bool    `json:"isBestMatch"`
+}
+
+// PreventedMatch is a match between a taker & maker order of the account that was prevented by
+// self-trade prevention.
+type PreventedMatch struct {
+	Symbol                  string                  `json:"symbol"`
+	PreventedMatchID        int64                   `json:"preventedMatchId"`
+	TakerOrderID            int64                   `json:"takerOrderId"`
+	MakerOrderID            int64                   `json:"makerOrderId"`
+	TradeGroupID            int64                   `json:"tradeGroupId"`
+	SelfTradePreventionMode SelfTradePreventionMode `json:"selfTradePreventionMode"`
+	Price                   float64                 `json:"price,string"`
+	// Quantity of the maker order that expired due to the prevented match.
+	MakerPreventedQuantity float64 `json:"makerPreventedQuantity,string"`
+	TransactTime           int64   `json:"transactTime"`
+}
+
+// Allocation is a fill of an account order that was routed by Smart Order Routing (SOR).
+type Allocation struct {
+	Symbol          string  `json:"symbol"`
+	AllocationID    int64   `json:"allocationId"`
+	AllocationType  string  `json:"allocationType"`
+	OrderID         int64   `json:"orderId"`
+	OrderListID     int64   `json:"orderListId"`
+	Price           float64 `json:"price,string"`
+	Qty             float64 `json:"qty,string"`
+	QuoteQty        float64 `json:"quoteQty,string"`
+	Commission      float64 `json:"commission,string"`
+	CommissionAsset string  `json:"commissionAsset"`
+	Time            int64   `json:"time"`
+	IsBuyer         bool    `json:"isBuyer"`
+	IsMaker         bool    `json:"isMaker"`
+	IsAllocator     bool    `json:"isAllocator"`
+}
+
+// Trade is a public trade fetched from the exchange.
+type Trade struct {
+	ID       int64   `json:"id"`
+	Price    float64 `json:"price,string"`
+	Qty      float64 `json:"qty,string"`
+	QuoteQty float64 `json:"quoteQty,string"`
+	Time     int64   `json:"time"`
+	// True if the buyer placed the order that was on the book, i.e. the taker was the seller.
+	IsBuyerMaker bool `json:"isBuyerMaker"`
+	IsBestMatch  bool `json:"isBestMatch"`
+}
+
+// TradeStats are statistics derived from a set of trades, volumes are in the base asset.
+type TradeStats struct {
+	// Volume of the trades where the taker was the buyer.
+	BuyVolume float64
+	// Volume of the trades where the taker was the seller.
+	SellVolume float64
+	// Volume weighted average price, zero if there was no volume.
+	VWAP       float64
+	HighPrice  float64
+	LowPrice   float64
+	TradeCount int
+}
+
+// AggregateTrades computes statistics for the given trades.
+func AggregateTrades(trades []Trade) TradeStats {
+	stats := TradeStats{TradeCount: len(trades)}
+	buyVolume := decimal.Zero
+	sellVolume := decimal.Zero
+	notional := decimal.Zero
+	for i, trade := range trades {
+		qty := decimal.NewFromFloat(trade.Qty)
+		if trade.IsBuyerMaker {
+			sellVolume = sellVolume.Add(qty)
+		} else {
+			buyVolume = buyVolume.Add(qty)
+		}
+		notional = notional.Add(qty.Mul(decimal.NewFromFloat(trade.Price)))
+		if i == 0 || trade.Price > stats.HighPrice {
+			stats.HighPrice = trade.Price
+		}
+		if i == 0 || trade.Price < stats.LowPrice {
+			stats.LowPrice = trade.Price
+		}
+	}
+	stats.BuyVolume, _ = buyVolume.Float64()
+	stats.SellVolume, _ = sellVolume.Float64()
+	if volume := buyVolume.Add(sellVolume); volume.IsPositive() {
+		stats.VWAP, _ = notional.DivRound(volume, 8).Float64()
+	}
+	return stats
+}
+
+// TotalCommissionByAsset returns the total commission charged for the given trades, keyed by
+// the asset the commission was charged in.
+func TotalCommissionByAsset(trades []AccountTrade) map[string]float64 {
+	totals := map[string]decimal.Decimal{}
+	for _, trade := range trades {
+		totals[trade.CommissionAsset] = totals[trade.CommissionAsset].Add(decimal.NewFromFloat(trade.Commission))
+	}
+	return commissionTotalsToFloats(totals)
+}
+
+func commissionTotalsToFloats(totals map[string]decimal.Decimal) map[string]float64 {
+	result := make(map[string]float64, len(totals))
+	for asset, total := range totals {
+		result[asset], _ = total.Float64()
+	}
+	return result
+}
+
 type DeleteOrderResponse struct {
 	Symbol            string `json:"symbol"`
 	OrigClientOrderID string `json:"origClientOrderId"`
@@ -178,3 +847,95 @@ type MarketData struct {
 	Bids         []OrderbookEntry `json:"bids"`
 	Asks         []OrderbookEntry `json:"asks"`
 }
+
+// DepthUpdateEvent is an event received via a diff depth stream.
+type DepthUpdateEvent struct {
+	EventType     string           `json:"e"`
+	EventTime     int64            `json:"E"`
+	Symbol        string           `json:"s"`
+	FirstUpdateID int64            `json:"U"`
+	FinalUpdateID int64            `json:"u"`
+	Bids          []OrderbookEntry `json:"b"`
+	Asks          []OrderbookEntry `json:"a"`
+}
+
+// UserDataEvent is an event received via the user data stream.
+type UserDataEvent struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	// The complete event message, it should be decoded into an event type specific struct.
+	Raw json.RawMessage `json:"-"`
+}
+
+// User data event types.
+const (
+	UserDataEventAccountPosition = "outboundAccountPosition"
+	UserDataEventExecutionReport = "executionReport"
+)
+
+// AccountPositionEvent is sent via the user data stream whenever the account balances change,
+// it contains the current balances of the assets that changed.
+type AccountPositionEvent struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	// Time of the last account update in milliseconds since the Unix epoch.
+	LastUpdateTime int64                    `json:"u"`
+	Balances       []AccountPositionBalance `json:"B"`
+}
+
+// ExecutionReportEvent is sent via the user data stream whenever an order is placed, updated, or
+// removed from the orderbook.
+type ExecutionReportEvent struct {
+	EventType     string      `json:"e"`
+	EventTime     int64       `json:"E"`
+	Symbol        string      `json:"s"`
+	ClientOrderID string      `json:"c"`
+	Side          OrderSide   `json:"S"`
+	Type          OrderType   `json:"o"`
+	TimeInForce   TimeInForce `json:"f"`
+	Quantity      float64     `json:"q,string"`
+	Price         float64     `json:"p,string"`
+	ExecutionType string      `json:"x"`
+	Status        OrderStatus `json:"X"`
+	OrderID       int64       `json:"i"`
+	// Quantity & price of the last fill, if any.
+	LastExecutedQty   float64 `json:"l,string"`
+	LastExecutedPrice float64 `json:"L,string"`
+	ExecutedQty       float64 `json:"z,string"`
+	// Time of the transaction in milliseconds since the Unix epoch.
+	TransactionTime int64 `json:"T"`
+	// The fields below must be declared even if unused, since encoding/json matches keys
+	// case-insensitively a key without a field would otherwise be decoded into the field of the
+	// key that only differs by case (e.g. "O" into Type).
+	OrigClientOrderID   string  `json:"C"` // ID of the order being canceled, if any
+	StopPrice           float64 `json:"P,string"`
+	IcebergQty          float64 `json:"F,string"`
+	QuoteOrderQty       float64 `json:"Q,string"`
+	CummulativeQuoteQty float64 `json:"Z,string"`
+	TradeID             int64   `json:"t"` // -1 if the event isn't a trade
+	// Time the order was created in milliseconds since the Unix epoch.
+	OrderCreationTime int64 `json:"O"`
+}
+
+// UnmarshalJSON decodes an execution report, ignoring the "I" key which would otherwise be decoded
+// into OrderID (see the comment above OrigClientOrderID).
+func (e *ExecutionReportEvent) UnmarshalJSON(b []byte) error {
+	type executionReportEvent ExecutionReportEvent
+	aux := struct {
+		*executionReportEvent
+		Ignore int64 `json:"I"`
+	}{executionReportEvent: (*executionReportEvent)(e)}
+	return json.Unmarshal(b, &aux)
+}
+
+// IsOpen returns true if the order is still open (i.e. new or partially filled).
+func (e *ExecutionReportEvent) IsOpen() bool {
+	return e.Status == OrderStatusNew || e.Status == OrderStatusPartial
+}
+
+// AccountPositionBalance is the current balance of an asset in an AccountPositionEvent.
+type AccountPositionBalance struct {
+	Asset  string  `json:"a"`
+	Free   float64 `json:"f,string"`
+	Locked float64 `json:"l,string"`
+}
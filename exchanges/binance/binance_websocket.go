@@ -0,0 +1,724 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mattkanwisher/cryptofiend/common"
+	"github.com/mattkanwisher/cryptofiend/currency/pair"
+	"github.com/mattkanwisher/cryptofiend/exchanges/orderbook"
+)
+
+const (
+	binanceWebsocketURL = "wss://stream.binance.com:9443/ws/"
+
+	// Listen keys expire after 60 minutes unless they're kept alive.
+	listenKeyKeepAliveInterval = 30 * time.Minute
+	// Number of levels to fetch when taking a snapshot of an orderbook that's maintained via a
+	// diff depth stream.
+	depthSnapshotLimit = 1000
+)
+
+// StartUserDataStream creates a listen key, connects to the user data stream, and keeps the
+// stream alive until the returned stop function is called. If the listen key can't be kept
+// alive, or the connection drops, the stream is re-established (possibly with a new listen key)
+// with an exponential backoff between attempts (see StreamBackoff), the events channel is closed
+// if the stream can't be re-established within the allowed number of attempts. Stopping the
+// stream closes the listen key, the connection, and the events channel. The returned listen key
+// is the one the stream was started with, it's replaced by a new one if the stream has to be
+// re-established with a new listen key, so it may no longer be valid after a reconnect.
+func (b *Binance) StartUserDataStream() (listenKey string, events <-chan UserDataEvent, stop func(), err error) {
+	listenKey, events, _, stop, err = b.startUserDataStream(nil)
+	return listenKey, events, stop, err
+}
+
+// StartUserDataStreamContext is like StartUserDataStream, but the stream is stopped when the given
+// context is done rather than by a stop function.
+func (b *Binance) StartUserDataStreamContext(ctx context.Context) (listenKey string, events <-chan UserDataEvent,
+	err error) {
+	if err = ctx.Err(); err != nil {
+		return "", nil, err
+	}
+	listenKey, events, finished, stop, err := b.startUserDataStream(nil)
+	if err != nil {
+		return "", nil, err
+	}
+	stopOnDone(ctx, stop, finished)
+	return listenKey, events, nil
+}
+
+// startUserDataStream starts a user data stream, the optional onReconnect function is called
+// from the stream goroutine every time the stream is re-established. If onReconnect returns an
+// error the new connection is dropped and re-established again, as if the reconnect had failed.
+// The returned finished channel is closed once the stream goroutine exits.
+func (b *Binance) startUserDataStream(onReconnect func() error) (listenKey string,
+	events <-chan UserDataEvent, finished <-chan struct{}, stop func(), err error) {
+	listenKey, err = b.CreateListenKey()
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+	conn, err := b.dialWebsocket(listenKey)
+	if err != nil {
+		b.CloseListenKey(listenKey)
+		return "", nil, nil, nil, err
+	}
+
+	s := &userDataStream{
+		b:           b,
+		listenKey:   listenKey,
+		events:      make(chan UserDataEvent, 100),
+		done:        make(chan struct{}),
+		finished:    make(chan struct{}),
+		onReconnect: onReconnect,
+	}
+	go s.run(conn)
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() { close(s.done) })
+	}
+	return listenKey, s.events, s.finished, stop, nil
+}
+
+func (b *Binance) dialWebsocket(stream string) (*wsConn, error) {
+	var dialer websocket.Dialer
+	conn, _, err := dialer.Dial(b.WebsocketURL+stream, http.Header{})
+	if err != nil {
+		return nil, err
+	}
+	return newWSConn(conn, wsReadTimeout), nil
+}
+
+type userDataStream struct {
+	b           *Binance
+	listenKey   string
+	events      chan UserDataEvent
+	done        chan struct{}
+	finished    chan struct{}
+	onReconnect func() error
+}
+
+// run keeps the user data stream alive until it's stopped.
+func (s *userDataStream) run(conn *wsConn) {
+	defer close(s.finished)
+	defer close(s.events)
+
+	keepAlive := time.NewTicker(listenKeyKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		if stopped := s.forward(conn, keepAlive.C); stopped {
+			conn.Close()
+			s.b.CloseListenKey(s.listenKey)
+			return
+		}
+		conn.Close()
+
+		conn = s.reconnect()
+		if conn == nil {
+			return // stopped or gave up while reconnecting
+		}
+	}
+}
+
+// forward forwards events from the given connection, and keeps the listen key alive, until the
+// stream is stopped or needs to be re-established. Returns true if the stream was stopped.
+func (s *userDataStream) forward(conn *wsConn, keepAlive <-chan time.Time) (stopped bool) {
+	for {
+		select {
+		case <-s.done:
+			return true
+		case <-keepAlive:
+			if err := s.b.KeepAliveListenKey(s.listenKey); err != nil {
+				s.b.logf("%s failed to keep user data stream alive: %s\n", s.b.GetName(), err)
+				return false
+			}
+		case msg, ok := <-conn.messages:
+			if !ok {
+				s.b.logf("%s user data stream disconnected: %s\n", s.b.GetName(), conn.Err())
+				return false
+			}
+			event := UserDataEvent{}
+			if err := common.JSONDecode(msg, &event); err != nil {
+				s.b.logf("%s failed to decode user data event: %s\n", s.b.GetName(), err)
+				continue
+			}
+			event.Raw = msg
+			select {
+			case s.events <- event:
+			case <-s.done:
+				return true
+			}
+		}
+	}
+}
+
+// reconnect re-establishes the user data stream, creating a new listen key if the current one
+// has expired, and calls onReconnect once connected. Returns nil if the stream was stopped, or the retries allowed by the stream backoff
+// policy were used up, before it could be re-established.
+func (s *userDataStream) reconnect() *wsConn {
+	bo := s.b.streamBackoff()
+	for {
+		if !bo.wait(s.done) {
+			s.b.CloseListenKey(s.listenKey)
+			return nil
+		}
+
+		err := s.b.KeepAliveListenKey(s.listenKey)
+		if err != nil {
+			var listenKey string
+			if listenKey, err = s.b.CreateListenKey(); err == nil {
+				s.listenKey = listenKey
+			}
+		}
+		if err == nil {
+			var conn *wsConn
+			if conn, err = s.b.dialWebsocket(s.listenKey); err == nil {
+				if s.onReconnect == nil {
+					return conn
+				}
+				if err = s.onReconnect(); err == nil {
+					return conn
+				}
+				conn.Close()
+			}
+		}
+		s.b.logf("%s failed to re-establish user data stream: %s\n", s.b.GetName(), err)
+	}
+}
+
+// StartLiveAccount seeds a local copy of the account information fetched from the exchange, and
+// then keeps the balances in it up to date by applying the account position events received via a
+// user data stream (see StartUserDataStream). The open orders are tracked the same way, seeded with
+// the open orders fetched from the exchange and updated by the execution report events. Unlike
+// FetchAccountInfo & FetchOpenOrders the seeding isn't throttled, so it never uses cached data.
+// The account information and open orders are re-fetched whenever the stream is re-established,
+// as events may have been missed while it was down (if re-fetching fails the stream is
+// re-established again). Use LiveAccount to obtain the current account information, and
+// CountOpenOrders to obtain the number of open orders. The local copies are discarded when the
+// returned stop function is called.
+func (b *Binance) StartLiveAccount() (stop func(), err error) {
+	stop, _, err = b.startLiveAccount()
+	return stop, err
+}
+
+// startLiveAccount starts maintaining the live account, the returned finished channel is closed
+// once the user data stream has finished and all its events have been applied.
+func (b *Binance) startLiveAccount() (stop func(), finished <-chan struct{}, err error) {
+	_, events, _, stopStream, err := b.startUserDataStream(b.seedLiveAccount)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = b.seedLiveAccount(); err != nil {
+		stopStream()
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range events {
+			switch event.EventType {
+			case UserDataEventAccountPosition:
+				position := AccountPositionEvent{}
+				if err := common.JSONDecode(event.Raw, &position); err != nil {
+					b.logf("%s failed to decode account position event: %s\n", b.GetName(), err)
+					continue
+				}
+				b.applyAccountPosition(&position)
+			case UserDataEventExecutionReport:
+				report := ExecutionReportEvent{}
+				if err := common.JSONDecode(event.Raw, &report); err != nil {
+					b.logf("%s failed to decode execution report event: %s\n", b.GetName(), err)
+					continue
+				}
+				b.applyExecutionReport(&report)
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			stopStream()
+			<-done
+			b.liveAccountMtx.Lock()
+			b.liveAccount = nil
+			b.liveOpenOrders = nil
+			b.liveAccountMtx.Unlock()
+		})
+	}
+	return stop, done, nil
+}
+
+// StartLiveAccountContext is like StartLiveAccount, but the local copies are discarded when the
+// given context is done rather than by a stop function.
+func (b *Binance) StartLiveAccountContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stop, finished, err := b.startLiveAccount()
+	if err != nil {
+		return err
+	}
+	stopOnDone(ctx, stop, finished)
+	return nil
+}
+
+// LiveAccount returns a copy of the account information maintained by StartLiveAccount, or nil if
+// it isn't running.
+func (b *Binance) LiveAccount() *AccountInfo {
+	b.liveAccountMtx.Lock()
+	defer b.liveAccountMtx.Unlock()
+	if b.liveAccount == nil {
+		return nil
+	}
+	return b.liveAccount.clone()
+}
+
+// liveOpenOrderCount returns the number of open orders for the given symbol maintained by
+// StartLiveAccount, the second return value is false if it isn't running.
+func (b *Binance) liveOpenOrderCount(symbol string) (int, bool) {
+	b.liveAccountMtx.Lock()
+	defer b.liveAccountMtx.Unlock()
+	if b.liveOpenOrders == nil {
+		return 0, false
+	}
+	return len(b.liveOpenOrders[symbol]), true
+}
+
+// liveOrderOpen returns true if the given order is one of the open orders maintained by
+// StartLiveAccount, the second return value is false if it isn't running.
+func (b *Binance) liveOrderOpen(symbol string, orderID int64) (open bool, live bool) {
+	b.liveAccountMtx.Lock()
+	defer b.liveAccountMtx.Unlock()
+	if b.liveOpenOrders == nil {
+		return false, false
+	}
+	_, open = b.liveOpenOrders[symbol][orderID]
+	return open, true
+}
+
+// seedLiveAccount replaces the live account information with freshly fetched account information,
+// unless the live account information is more recent, and replaces the live open orders with
+// freshly fetched open orders.
+func (b *Binance) seedLiveAccount() error {
+	// Fetched directly, FetchAccountInfo & FetchOpenOrders may return cached data when throttled.
+	info, err := b.fetchAccountInfo()
+	if err != nil {
+		return err
+	}
+	orders, err := b.fetchOpenOrders("")
+	if err != nil {
+		return err
+	}
+	openOrders := make(map[string]map[int64]struct{})
+	for _, order := range orders {
+		if openOrders[order.Symbol] == nil {
+			openOrders[order.Symbol] = make(map[int64]struct{})
+		}
+		openOrders[order.Symbol][order.OrderID] = struct{}{}
+	}
+	b.liveAccountMtx.Lock()
+	defer b.liveAccountMtx.Unlock()
+	if b.liveAccount == nil || info.UpdateTime >= b.liveAccount.UpdateTime {
+		b.liveAccount = info.clone()
+	}
+	b.liveOpenOrders = openOrders
+	return nil
+}
+
+// applyExecutionReport adds the order in the given event to the live open orders if it's open,
+// and removes it otherwise.
+func (b *Binance) applyExecutionReport(report *ExecutionReportEvent) {
+	b.liveAccountMtx.Lock()
+	defer b.liveAccountMtx.Unlock()
+	if b.liveOpenOrders == nil {
+		return
+	}
+	orders := b.liveOpenOrders[report.Symbol]
+	if !report.IsOpen() {
+		delete(orders, report.OrderID)
+		return
+	}
+	if orders == nil {
+		orders = make(map[int64]struct{})
+		b.liveOpenOrders[report.Symbol] = orders
+	}
+	orders[report.OrderID] = struct{}{}
+}
+
+// applyAccountPosition updates the live account balances with those in the given event, unless
+// the event predates the live account information.
+func (b *Binance) applyAccountPosition(event *AccountPositionEvent) {
+	b.liveAccountMtx.Lock()
+	defer b.liveAccountMtx.Unlock()
+	account := b.liveAccount
+	if account == nil || event.LastUpdateTime < account.UpdateTime {
+		return
+	}
+	account.UpdateTime = event.LastUpdateTime
+	for _, update := range event.Balances {
+		found := false
+		for _, balance := range account.Balances {
+			if balance.Asset == update.Asset {
+				balance.Free = update.Free
+				balance.Locked = update.Locked
+				found = true
+				break
+			}
+		}
+		if !found {
+			account.Balances = append(account.Balances,
+				&Balance{Asset: update.Asset, Free: update.Free, Locked: update.Locked})
+		}
+	}
+}
+
+// StreamDepthInto subscribes to the diff depth streams of the given symbols, and maintains a local
+// orderbook for each symbol by reconciling the stream with a REST snapshot of the orderbook. Every
+// update to a local orderbook is passed on to the given store. If a stream drops, or an update is
+// missed, the orderbook is re-synced with an exponential backoff between attempts (see
+// StreamBackoff). The streams keep running until the returned stop function is called, or until a
+// stream can't be re-established within the allowed number of attempts.
+func (b *Binance) StreamDepthInto(store *orderbook.Orderbooks, symbols []string, orderbookType string) (stop func(), err error) {
+	stop, _, err = b.streamDepthInto(store, symbols, orderbookType)
+	return stop, err
+}
+
+// streamDepthInto starts the depth streams, the returned finished channel is closed once all the
+// streams have finished.
+func (b *Binance) streamDepthInto(store *orderbook.Orderbooks, symbols []string,
+	orderbookType string) (stop func(), finished <-chan struct{}, err error) {
+	streams := make([]*depthStream, 0, len(symbols))
+	conns := make([]*wsConn, 0, len(symbols))
+	done := make(chan struct{})
+	for _, symbol := range symbols {
+		var p pair.CurrencyPair
+		var conn *wsConn
+		if p, err = b.SymbolToCurrencyPair(symbol); err == nil {
+			conn, err = b.dialWebsocket(depthStreamName(symbol))
+		}
+		if err != nil {
+			for _, conn := range conns {
+				conn.Close()
+			}
+			return nil, nil, err
+		}
+		streams = append(streams, &depthStream{
+			b:             b,
+			symbol:        symbol,
+			pair:          p,
+			store:         store,
+			orderbookType: orderbookType,
+			done:          done,
+		})
+		conns = append(conns, conn)
+	}
+
+	var wg sync.WaitGroup
+	for i, s := range streams {
+		wg.Add(1)
+		go func(s *depthStream, conn *wsConn) {
+			defer wg.Done()
+			s.run(conn)
+		}(s, conns[i])
+	}
+
+	allFinished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(allFinished)
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+	return stop, allFinished, nil
+}
+
+// StreamDepthIntoContext is like StreamDepthInto, but the streams are stopped when the given
+// context is done rather than by a stop function.
+func (b *Binance) StreamDepthIntoContext(ctx context.Context, store *orderbook.Orderbooks, symbols []string, orderbookType string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stop, finished, err := b.streamDepthInto(store, symbols, orderbookType)
+	if err != nil {
+		return err
+	}
+	stopOnDone(ctx, stop, finished)
+	return nil
+}
+
+func depthStreamName(symbol string) string {
+	return strings.ToLower(symbol) + "@depth@100ms"
+}
+
+type depthStream struct {
+	b             *Binance
+	symbol        string
+	pair          pair.CurrencyPair
+	store         *orderbook.Orderbooks
+	orderbookType string
+	done          chan struct{}
+}
+
+// run maintains the local orderbook until the stream is stopped.
+func (s *depthStream) run(conn *wsConn) {
+	for {
+		err := s.sync(conn)
+		if err == nil {
+			return // stopped
+		}
+		s.b.logf("%s %s depth stream out of sync: %s\n", s.b.GetName(), s.symbol, err)
+
+		conn = s.reconnect()
+		if conn == nil {
+			return // stopped or gave up while reconnecting
+		}
+	}
+}
+
+// sync fetches a snapshot of the orderbook and applies the updates received via the given
+// connection to it until the connection fails, an update is missed, or the stream is stopped.
+// Returns nil if the stream was stopped. The connection is always closed on return.
+func (s *depthStream) sync(conn *wsConn) error {
+	defer conn.Close()
+
+	// Updates received while the snapshot is being fetched are buffered by the connection, the
+	// snapshot may be older than some of the buffered updates. The snapshot is fetched directly
+	// rather than via FetchMarketData, whose throttling is shared by all symbols (so concurrent
+	// streams would get a stale cached snapshot) and isn't safe for concurrent use.
+	snapshot, err := s.b.fetchMarketData(s.symbol, depthSnapshotLimit)
+	if err != nil {
+		return err
+	}
+	book := orderbook.Base{
+		Bids: orderbookItems(snapshot.Bids),
+		Asks: orderbookItems(snapshot.Asks),
+	}
+	lastUpdateID := snapshot.LastUpdateID
+	synced := false
+
+	for {
+		select {
+		case <-s.done:
+			return nil
+		case msg, ok := <-conn.messages:
+			if !ok {
+				return conn.Err()
+			}
+			event := DepthUpdateEvent{}
+			if err := common.JSONDecode(msg, &event); err != nil {
+				return err
+			}
+			if event.FinalUpdateID <= lastUpdateID {
+				continue // already included in the snapshot
+			}
+			// The first update applied to the snapshot must overlap it, every following update
+			// must start right after the previous one.
+			if (!synced && event.FirstUpdateID > lastUpdateID+1) ||
+				(synced && event.FirstUpdateID != lastUpdateID+1) {
+				return fmt.Errorf("missed updates %d to %d", lastUpdateID+1, event.FirstUpdateID-1)
+			}
+			synced = true
+			lastUpdateID = event.FinalUpdateID
+			book.ApplyDelta(orderbookItems(event.Bids), orderbookItems(event.Asks))
+			if err := s.store.ProcessOrderbook(s.b.Name, s.pair, book.Clone(), s.orderbookType); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// reconnect re-establishes the depth stream. Returns nil if the stream was stopped, or the retries
+// allowed by the stream backoff policy were used up, before it could be re-established.
+func (s *depthStream) reconnect() *wsConn {
+	bo := s.b.streamBackoff()
+	for {
+		if !bo.wait(s.done) {
+			return nil
+		}
+
+		conn, err := s.b.dialWebsocket(depthStreamName(s.symbol))
+		if err == nil {
+			return conn
+		}
+		s.b.logf("%s failed to re-establish %s depth stream: %s\n", s.b.GetName(), s.symbol, err)
+	}
+}
+
+func orderbookItems(entries []OrderbookEntry) []orderbook.Item {
+	items := make([]orderbook.Item, len(entries))
+	for i, entry := range entries {
+		items[i] = orderbook.Item{Price: entry.Price, Amount: entry.Quantity}
+	}
+	return items
+}
+
+// SubscribePartialDepth subscribes to the partial depth stream of the given symbol, which pushes
+// a snapshot of the top levels (5, 10, or 20) of the orderbook every updateSpeed (100ms or 1s).
+// Each snapshot is delivered as is, if the receiver falls behind older snapshots are dropped in
+// favour of the latest one. If the connection drops it's re-established with an exponential
+// backoff between attempts (see StreamBackoff), the channel is closed if it can't be
+// re-established within the allowed number of attempts. The returned stop function closes the
+// connection and the channel.
+func (b *Binance) SubscribePartialDepth(symbol string, levels int, updateSpeed time.Duration) (<-chan orderbook.Base, func(), error) {
+	books, _, stop, err := b.subscribePartialDepth(symbol, levels, updateSpeed)
+	return books, stop, err
+}
+
+// subscribePartialDepth subscribes to a partial depth stream, the returned finished channel is
+// closed once the stream goroutine exits.
+func (b *Binance) subscribePartialDepth(symbol string, levels int, updateSpeed time.Duration) (<-chan orderbook.Base,
+	<-chan struct{}, func(), error) {
+	if levels != 5 && levels != 10 && levels != 20 {
+		return nil, nil, nil, fmt.Errorf("invalid partial depth levels %d, must be 5, 10, or 20", levels)
+	}
+	stream := fmt.Sprintf("%s@depth%d", strings.ToLower(symbol), levels)
+	switch updateSpeed {
+	case time.Second:
+	case 100 * time.Millisecond:
+		stream += "@100ms"
+	default:
+		return nil, nil, nil, fmt.Errorf("invalid partial depth update speed %v, must be 100ms or 1s", updateSpeed)
+	}
+	conn, err := b.dialWebsocket(stream)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	s := &partialDepthStream{
+		b:        b,
+		stream:   stream,
+		books:    make(chan orderbook.Base, 1),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	// The pair is only known if the markets have been loaded.
+	s.pair, _ = b.SymbolToCurrencyPair(symbol)
+	go s.run(conn)
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() { close(s.done) })
+	}
+	return s.books, s.finished, stop, nil
+}
+
+// SubscribePartialDepthContext is like SubscribePartialDepth, but the connection and the channel
+// are closed when the given context is done rather than by a stop function.
+func (b *Binance) SubscribePartialDepthContext(ctx context.Context, symbol string, levels int, updateSpeed time.Duration) (<-chan orderbook.Base, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	books, finished, stop, err := b.subscribePartialDepth(symbol, levels, updateSpeed)
+	if err != nil {
+		return nil, err
+	}
+	stopOnDone(ctx, stop, finished)
+	return books, nil
+}
+
+// stopOnDone calls the given stop function once the given context is done, or once the stream
+// has finished on its own (e.g. it couldn't be re-established) so the goroutine doesn't outlive
+// the stream.
+func stopOnDone(ctx context.Context, stop func(), finished <-chan struct{}) {
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-finished:
+		}
+		stop()
+	}()
+}
+
+type partialDepthStream struct {
+	b        *Binance
+	stream   string
+	pair     pair.CurrencyPair
+	books    chan orderbook.Base
+	done     chan struct{}
+	finished chan struct{}
+}
+
+// run forwards snapshots until the stream is stopped.
+func (s *partialDepthStream) run(conn *wsConn) {
+	defer close(s.finished)
+	defer close(s.books)
+	for {
+		if stopped := s.forward(conn); stopped {
+			conn.Close()
+			return
+		}
+		conn.Close()
+		s.b.logf("%s %s stream disconnected: %s\n", s.b.GetName(), s.stream, conn.Err())
+
+		conn = s.reconnect()
+		if conn == nil {
+			return // stopped or gave up while reconnecting
+		}
+	}
+}
+
+// forward forwards snapshots from the given connection until the connection fails or the stream
+// is stopped. Returns true if the stream was stopped.
+func (s *partialDepthStream) forward(conn *wsConn) (stopped bool) {
+	for {
+		select {
+		case <-s.done:
+			return true
+		case msg, ok := <-conn.messages:
+			if !ok {
+				return false
+			}
+			snapshot := MarketData{}
+			if err := common.JSONDecode(msg, &snapshot); err != nil {
+				s.b.logf("%s failed to decode %s snapshot: %s\n", s.b.GetName(), s.stream, err)
+				continue
+			}
+			book := orderbook.Base{
+				Pair:        s.pair,
+				Bids:        orderbookItems(snapshot.Bids),
+				Asks:        orderbookItems(snapshot.Asks),
+				LastUpdated: time.Now(),
+			}
+			if s.pair != (pair.CurrencyPair{}) {
+				book.CurrencyPair = s.pair.Pair().String()
+			}
+			// Replace the pending snapshot (if any) so the receiver always gets the latest one.
+			select {
+			case <-s.books:
+			default:
+			}
+			s.books <- book
+		}
+	}
+}
+
+// reconnect re-establishes the stream. Returns nil if the stream was stopped, or the retries
+// allowed by the stream backoff policy were used up, before it could be re-established.
+func (s *partialDepthStream) reconnect() *wsConn {
+	bo := s.b.streamBackoff()
+	for {
+		if !bo.wait(s.done) {
+			return nil
+		}
+
+		conn, err := s.b.dialWebsocket(s.stream)
+		if err == nil {
+			return conn
+		}
+		s.b.logf("%s failed to re-establish %s stream: %s\n", s.b.GetName(), s.stream, err)
+	}
+}
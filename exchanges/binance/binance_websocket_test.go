@@ -0,0 +1,506 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mattkanwisher/cryptofiend/currency/pair"
+	"github.com/mattkanwisher/cryptofiend/exchanges/orderbook"
+)
+
+// newTestWebsocketServer returns a test server that accepts websocket connections to any path
+// under /ws/ and passes them to the given handler, all other requests are passed to the REST
+// handler.
+func newTestWebsocketServer(rest http.HandlerFunc, ws func(stream string, conn *websocket.Conn)) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/ws/") {
+			rest(w, r)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		ws(strings.TrimPrefix(r.URL.Path, "/ws/"), conn)
+	}))
+}
+
+// newTestWebsocketBinance returns a Binance instance that sends all REST & websocket requests to
+// the given test server.
+func newTestWebsocketBinance(server *httptest.Server) *Binance {
+	b := newTestBinance(server)
+	b.WebsocketURL = "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/"
+	return b
+}
+
+func TestStartUserDataStream(t *testing.T) {
+	t.Parallel()
+	closed := make(chan string, 1)
+	server := newTestWebsocketServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			fmt.Fprint(w, `{"listenKey":"testListenKey"}`)
+		case http.MethodDelete:
+			body, _ := ioutil.ReadAll(r.Body)
+			v, _ := url.ParseQuery(string(body))
+			closed <- v.Get("listenKey")
+			fmt.Fprint(w, `{}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}, func(stream string, conn *websocket.Conn) {
+		if stream != "testListenKey" {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"e":"outboundAccountPosition","E":1564034571105,"u":1564034571073,"B":[]}`))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	b := newTestWebsocketBinance(server)
+	listenKey, events, stop, err := b.StartUserDataStream()
+	if err != nil {
+		t.Fatalf("Test failed. StartUserDataStream returned error: %s", err)
+	}
+	if listenKey != "testListenKey" {
+		t.Errorf("Test failed. Unexpected listen key %s", listenKey)
+	}
+
+	select {
+	case event := <-events:
+		if event.EventType != "outboundAccountPosition" || event.EventTime != 1564034571105 ||
+			len(event.Raw) == 0 {
+			t.Errorf("Test failed. Unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Timed out waiting for user data event")
+	}
+
+	stop()
+	select {
+	case key := <-closed:
+		if key != "testListenKey" {
+			t.Errorf("Test failed. Closed unexpected listen key %s", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Timed out waiting for listen key to be closed")
+	}
+	for range events {
+	}
+}
+
+// testExecutionReport returns an execution report in the full format sent by Binance, for the
+// given order ID & status with the given event/transaction time.
+func testExecutionReport(eventTime int64, symbol string, orderID int64, status OrderStatus) string {
+	return fmt.Sprintf(`{"e":"executionReport","E":%[1]d,"s":"%[2]s","c":"mUvoqJxFIILMdfAW5iGSOW",`+
+		`"S":"BUY","o":"LIMIT","f":"GTC","q":"1.00000000","p":"0.10264410","P":"0.00000000",`+
+		`"F":"0.00000000","g":-1,"C":"","x":"TRADE","X":"%[4]s","r":"NONE","i":%[3]d,`+
+		`"l":"0.00000000","z":"0.00000000","L":"0.00000000","n":"0","N":null,"T":%[1]d,"t":-1,`+
+		`"v":3,"I":8641984,"w":true,"m":false,"M":false,"O":%[1]d,"Z":"0.00000000",`+
+		`"Y":"0.00000000","Q":"0.00000000","W":%[1]d,"V":"NONE"}`, eventTime, symbol, orderID, status)
+}
+
+func TestLiveAccount(t *testing.T) {
+	t.Parallel()
+	server := newTestWebsocketServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + binanceAccountPath:
+			fmt.Fprint(w, `{"canTrade":true,"updateTime":100,"balances":[
+				{"asset":"BTC","free":"1.0","locked":"0.0"},{"asset":"ETH","free":"2.0","locked":"0.0"}]}`)
+		case "/" + binanceOpenOrdersPath:
+			fmt.Fprint(w, `[{"symbol":"BNBBTC","orderId":1,"status":"NEW"},
+				{"symbol":"BNBBTC","orderId":2,"status":"PARTIALLY_FILLED"}]`)
+		case "/" + binanceUserDataStreamPath:
+			fmt.Fprint(w, `{"listenKey":"testListenKey"}`)
+		default:
+			t.Errorf("Test failed. Unexpected request to %s", r.URL.Path)
+		}
+	}, func(stream string, conn *websocket.Conn) {
+		// The first event predates the account info and should be ignored.
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"outboundAccountPosition","E":60,"u":50,`+
+			`"B":[{"a":"ETH","f":"5.0","l":"0.0"}]}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(testExecutionReport(130, "BNBBTC", 2, "FILLED")))
+		conn.WriteMessage(websocket.TextMessage, []byte(testExecutionReport(140, "XYZBTC", 3, "NEW")))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"outboundAccountPosition","E":160,"u":150,`+
+			`"B":[{"a":"BTC","f":"0.5","l":"0.5"},{"a":"BNB","f":"10.0","l":"0.0"}]}`))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	b := newTestWebsocketBinance(server)
+	if b.LiveAccount() != nil {
+		t.Error("Test failed. LiveAccount should return nil before it's started")
+	}
+	stop, err := b.StartLiveAccount()
+	if err != nil {
+		t.Fatalf("Test failed. StartLiveAccount returned error: %s", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	account := b.LiveAccount()
+	for account.UpdateTime != 150 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		account = b.LiveAccount()
+	}
+	balances := account.BalanceMap()
+	if account.UpdateTime != 150 || len(balances) != 3 || balances["BTC"].Free != 0.5 ||
+		balances["BTC"].Locked != 0.5 || balances["ETH"].Free != 2 || balances["BNB"].Free != 10 {
+		t.Errorf("Test failed. Unexpected live account %+v", balances)
+	}
+
+	for symbol, expected := range map[string]int{"BNBBTC": 1, "XYZBTC": 1, "ABCBTC": 0} {
+		if count, err := b.CountOpenOrders(symbol); err != nil || count != expected {
+			t.Errorf("Test failed. CountOpenOrders(%s) returned %d, error %v, expected %d", symbol, count,
+				err, expected)
+		}
+	}
+
+	stop()
+	if b.LiveAccount() != nil {
+		t.Error("Test failed. LiveAccount should return nil after it's stopped")
+	}
+	// Once stopped the open orders should be fetched.
+	b.rateLimits = map[string]int64{}
+	if count, err := b.CountOpenOrders("BNBBTC"); err != nil || count != 2 {
+		t.Errorf("Test failed. CountOpenOrders returned %d, error %v, expected 2", count, err)
+	}
+}
+
+func TestLiveAccountReseed(t *testing.T) {
+	t.Parallel()
+	var accountFetches int32
+	seeded := make(chan struct{})
+	server := newTestWebsocketServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + binanceAccountPath:
+			switch atomic.AddInt32(&accountFetches, 1) {
+			case 1, 2: // fetched before the live account is started, and by the initial seed
+				fmt.Fprint(w, `{"updateTime":100,"balances":[{"asset":"BTC","free":"1.0","locked":"0.0"}]}`)
+			case 3: // the first reseed fails
+				w.WriteHeader(http.StatusInternalServerError)
+			default:
+				fmt.Fprint(w, `{"updateTime":200,"balances":[{"asset":"BTC","free":"3.0","locked":"0.0"}]}`)
+			}
+			if atomic.LoadInt32(&accountFetches) == 2 {
+				close(seeded)
+			}
+		case "/" + binanceOpenOrdersPath:
+			// Order 2 was filled while the stream was down.
+			if atomic.LoadInt32(&accountFetches) >= 4 {
+				fmt.Fprint(w, `[{"symbol":"BNBBTC","orderId":1,"status":"NEW"}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"symbol":"BNBBTC","orderId":1,"status":"NEW"},
+				{"symbol":"BNBBTC","orderId":2,"status":"NEW"}]`)
+		case "/" + binanceUserDataStreamPath:
+			fmt.Fprint(w, `{"listenKey":"testListenKey"}`)
+		default:
+			t.Errorf("Test failed. Unexpected request to %s", r.URL.Path)
+		}
+	}, func(stream string, conn *websocket.Conn) {
+		select {
+		case <-seeded:
+		case <-time.After(5 * time.Second):
+		}
+		if atomic.LoadInt32(&accountFetches) == 2 {
+			return // drop the first connection straight after the initial seed
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	b := newTestWebsocketBinance(server)
+	b.StreamBackoff = &BackoffPolicy{BaseDelay: 10 * time.Millisecond}
+	// The live account must be seeded from the exchange even if the throttled fetcher was just used.
+	if _, err := b.FetchAccountInfo(); err != nil {
+		t.Fatalf("Test failed. FetchAccountInfo returned error: %s", err)
+	}
+	if _, err := b.FetchOpenOrders(""); err != nil {
+		t.Fatalf("Test failed. FetchOpenOrders returned error: %s", err)
+	}
+	stop, err := b.StartLiveAccount()
+	if err != nil {
+		t.Fatalf("Test failed. StartLiveAccount returned error: %s", err)
+	}
+	defer stop()
+
+	// The reseed after the reconnect fails, so the stream is re-established and reseeded again.
+	deadline := time.Now().Add(5 * time.Second)
+	account := b.LiveAccount()
+	for account.UpdateTime != 200 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		account = b.LiveAccount()
+	}
+	if balances := account.BalanceMap(); account.UpdateTime != 200 || balances["BTC"].Free != 3 {
+		t.Errorf("Test failed. Live account wasn't reseeded after reconnecting %+v", balances)
+	}
+	if fetches := atomic.LoadInt32(&accountFetches); fetches < 4 {
+		t.Errorf("Test failed. Expected the failed reseed to be retried, account fetched %d times", fetches)
+	}
+	// The open orders are reseeded right after the account info, and the reconnect happened right
+	// after the initial seed, so a throttled fetch would have returned the stale open orders.
+	count, _ := b.CountOpenOrders("BNBBTC")
+	for count != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		count, _ = b.CountOpenOrders("BNBBTC")
+	}
+	if count != 1 {
+		t.Errorf("Test failed. Live open orders weren't reseeded after reconnecting, count %d", count)
+	}
+	if open, live := b.liveOrderOpen("BNBBTC", 2); open || !live {
+		t.Errorf("Test failed. Order filled while the stream was down still open %v, live %v", open, live)
+	}
+}
+
+func TestLiveAccountExecutionReports(t *testing.T) {
+	t.Parallel()
+	reports := make(chan string)
+	server := newTestWebsocketServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + binanceAccountPath:
+			fmt.Fprint(w, `{"updateTime":100,"balances":[{"asset":"BTC","free":"1.0","locked":"0.0"}]}`)
+		case "/" + binanceOpenOrdersPath:
+			fmt.Fprint(w, `[]`)
+		case "/" + binanceUserDataStreamPath:
+			fmt.Fprint(w, `{"listenKey":"testListenKey"}`)
+		default:
+			t.Errorf("Test failed. Unexpected request to %s", r.URL.Path)
+		}
+	}, func(stream string, conn *websocket.Conn) {
+		for report := range reports {
+			conn.WriteMessage(websocket.TextMessage, []byte(report))
+		}
+	})
+	defer server.Close()
+
+	b := newTestWebsocketBinance(server)
+	stop, err := b.StartLiveAccount()
+	if err != nil {
+		t.Fatalf("Test failed. StartLiveAccount returned error: %s", err)
+	}
+	defer stop()
+
+	waitForCount := func(expected int) {
+		deadline := time.Now().Add(5 * time.Second)
+		count, err := b.CountOpenOrders("ETHBTC")
+		for count != expected && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			count, err = b.CountOpenOrders("ETHBTC")
+		}
+		if err != nil || count != expected {
+			t.Errorf("Test failed. CountOpenOrders returned %d, error %v, expected %d", count, err, expected)
+		}
+	}
+	waitForCount(0)
+	reports <- testExecutionReport(200, "ETHBTC", 4293153, OrderStatusNew)
+	waitForCount(1)
+	reports <- testExecutionReport(300, "ETHBTC", 4293153, OrderStatusFilled)
+	waitForCount(0)
+	close(reports)
+}
+
+func TestStreamDepthInto(t *testing.T) {
+	t.Parallel()
+	server := newTestWebsocketServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("symbol") == "XYZBTC" {
+			fmt.Fprint(w, `{"lastUpdateId":200,"bids":[["0.0001","10"]],"asks":[["0.0002","10"]]}`)
+			return
+		}
+		fmt.Fprint(w, `{"lastUpdateId":100,"bids":[["0.0024","10"]],"asks":[["0.0026","100"]]}`)
+	}, func(stream string, conn *websocket.Conn) {
+		switch stream {
+		case "bnbbtc@depth@100ms":
+		case "xyzbtc@depth@100ms":
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"depthUpdate","E":1,"s":"XYZBTC","U":201,`+
+				`"u":201,"b":[["0.00011","2"]],"a":[]}`))
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		default:
+			return
+		}
+		// The first update is already included in the snapshot, the second overlaps it.
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"depthUpdate","E":1,"s":"BNBBTC","U":95,`+
+			`"u":99,"b":[["0.0024","1"]],"a":[]}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"depthUpdate","E":2,"s":"BNBBTC","U":99,`+
+			`"u":101,"b":[["0.0024","0"],["0.0023","5"]],"a":[]}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"depthUpdate","E":3,"s":"BNBBTC","U":102,`+
+			`"u":102,"b":[],"a":[["0.0025","1"]]}`))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	b := newTestMarketsBinance(t)
+	b.APIUrl = server.URL + "/"
+	b.WebsocketURL = "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/"
+	store := orderbook.Init()
+	// Each symbol's stream fetches its own snapshot, concurrently with the other streams, even if the
+	// throttled fetcher was just used.
+	if _, err := b.FetchMarketData("BNBBTC", 100); err != nil {
+		t.Fatalf("Test failed. FetchMarketData returned error: %s", err)
+	}
+	stop, err := b.StreamDepthInto(&store, []string{"BNBBTC", "XYZBTC"}, "SPOT")
+	if err != nil {
+		t.Fatalf("Test failed. StreamDepthInto returned error: %s", err)
+	}
+	defer stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, tc := range []struct {
+		p            pair.CurrencyPair
+		expectedBids []orderbook.Item
+		expectedAsks []orderbook.Item
+	}{
+		{
+			pair.NewCurrencyPair("BNB", "BTC"),
+			[]orderbook.Item{{Price: 0.0023, Amount: 5}},
+			[]orderbook.Item{{Price: 0.0025, Amount: 1}, {Price: 0.0026, Amount: 100}},
+		},
+		{
+			pair.NewCurrencyPair("XYZ", "BTC"),
+			[]orderbook.Item{{Price: 0.00011, Amount: 2}, {Price: 0.0001, Amount: 10}},
+			[]orderbook.Item{{Price: 0.0002, Amount: 10}},
+		},
+	} {
+		for {
+			book, ok := store.LookupOrderbook(tc.p, "SPOT")
+			if ok && reflect.DeepEqual(book.Bids, tc.expectedBids) && reflect.DeepEqual(book.Asks, tc.expectedAsks) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Test failed. Unexpected %s orderbook %+v", tc.p.Pair(), book)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if _, err := b.StreamDepthInto(&store, []string{"UNKNOWN"}, "SPOT"); err == nil {
+		t.Error("Test failed. StreamDepthInto should fail for an unknown symbol")
+	}
+}
+
+func TestSubscribePartialDepth(t *testing.T) {
+	t.Parallel()
+	server := newTestWebsocketServer(nil, func(stream string, conn *websocket.Conn) {
+		if stream != "bnbbtc@depth5@100ms" {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"lastUpdateId":160,`+
+			`"bids":[["0.0024","10"],["0.0023","5"]],"asks":[["0.0026","100"]]}`))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	b := newTestWebsocketBinance(server)
+	if _, _, err := b.SubscribePartialDepth("BNBBTC", 15, time.Second); err == nil {
+		t.Error("Test failed. SubscribePartialDepth should reject invalid levels")
+	}
+	if _, _, err := b.SubscribePartialDepth("BNBBTC", 5, time.Minute); err == nil {
+		t.Error("Test failed. SubscribePartialDepth should reject an invalid update speed")
+	}
+
+	books, stop, err := b.SubscribePartialDepth("BNBBTC", 5, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Test failed. SubscribePartialDepth returned error: %s", err)
+	}
+	select {
+	case book := <-books:
+		expectedBids := []orderbook.Item{{Price: 0.0024, Amount: 10}, {Price: 0.0023, Amount: 5}}
+		expectedAsks := []orderbook.Item{{Price: 0.0026, Amount: 100}}
+		if !reflect.DeepEqual(book.Bids, expectedBids) || !reflect.DeepEqual(book.Asks, expectedAsks) ||
+			book.LastUpdated.IsZero() {
+			t.Errorf("Test failed. Unexpected orderbook %+v", book)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Timed out waiting for orderbook")
+	}
+
+	stop()
+	for range books {
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	books, err = b.SubscribePartialDepthContext(ctx, "BNBBTC", 5, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Test failed. SubscribePartialDepthContext returned error: %s", err)
+	}
+	select {
+	case <-books:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Timed out waiting for orderbook")
+	}
+	cancel()
+	closed := make(chan struct{})
+	go func() {
+		for range books {
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Channel wasn't closed when the context was cancelled")
+	}
+	if _, err = b.SubscribePartialDepthContext(ctx, "BNBBTC", 5, 100*time.Millisecond); err != context.Canceled {
+		t.Errorf("Test failed. Expected context.Canceled for a cancelled context, got %v", err)
+	}
+}
+
+func TestStopOnDone(t *testing.T) {
+	t.Parallel()
+	// A stream that finishes on its own releases the watcher even if the context is never done.
+	finished := make(chan struct{})
+	stopped := make(chan struct{})
+	stopOnDone(context.Background(), func() { close(stopped) }, finished)
+	close(finished)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Stop wasn't called when the stream finished")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped = make(chan struct{})
+	stopOnDone(ctx, func() { close(stopped) }, make(chan struct{}))
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Stop wasn't called when the context was cancelled")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mattkanwisher/cryptofiend/common"
 	"github.com/mattkanwisher/cryptofiend/config"
@@ -21,15 +22,19 @@ func (b *Binance) SetDefaults() {
 	b.Verbose = false
 	b.Websocket = false
 	b.RESTPollingDelay = 10
+	b.APIUrl = binanceBaseURL
+	b.WebsocketURL = binanceWebsocketURL
 	b.RequestCurrencyPairFormat.Delimiter = ""
 	b.RequestCurrencyPairFormat.Uppercase = true
 	b.ConfigCurrencyPairFormat.Delimiter = ""
 	b.ConfigCurrencyPairFormat.Uppercase = true
 	b.AssetTypes = []string{ticker.Spot}
 	b.Orderbooks = orderbook.Init()
+	b.Logger = noopLogger{}
 	b.rateLimits = map[string]int64{}
 	b.lastOpenOrders = map[string][]Order{}
 	b.lastMarketData = map[string]*MarketData{}
+	b.nowFunc = time.Now
 }
 
 // Setup takes in the supplied exchange configuration details and sets params
@@ -64,41 +69,21 @@ func (b *Binance) Start() {
 
 // Run implements the Binance wrapper
 func (b *Binance) Run() {
-	if b.Verbose {
-		log.Printf("%s polling delay: %ds.\n", b.GetName(), b.RESTPollingDelay)
-		log.Printf("%s %d currencies enabled: %s.\n", b.GetName(), len(b.EnabledPairs), b.EnabledPairs)
-	}
+	b.logf("%s polling delay: %ds.\n", b.GetName(), b.RESTPollingDelay)
+	b.logf("%s %d currencies enabled: %s.\n", b.GetName(), len(b.EnabledPairs), b.EnabledPairs)
 
-	exchangeInfo, err := b.FetchExchangeInfo()
+	err := b.LoadMarkets()
 	if err != nil {
 		log.Printf("%s failed to get exchange info\n", b.GetName())
 		return
 	}
 
-	exchangeProducts := make([]string, len(exchangeInfo.Symbols))
-	b.currencyPairs = make(map[pair.CurrencyItem]*exchange.CurrencyPairInfo, len(exchangeInfo.Symbols))
-	b.symbolDetailsMap = make(map[pair.CurrencyItem]*symbolDetails, len(exchangeInfo.Symbols))
-	for i := range exchangeInfo.Symbols {
-		symbolInfo := &exchangeInfo.Symbols[i]
-		exchangeProducts[i] = symbolInfo.Symbol
-		currencyPair := pair.NewCurrencyPair(symbolInfo.BaseAsset, symbolInfo.QuoteAsset)
-		b.currencyPairs[pair.CurrencyItem(symbolInfo.Symbol)] = &exchange.CurrencyPairInfo{Currency: currencyPair}
-		sd := symbolDetails{}
-		for _, filter := range symbolInfo.Filters {
-			switch filter.Type {
-			case FilterTypePrice:
-				sd.PriceDecimalPlaces = filter.TickSize.Exponent() * -1
-			case FilterTypeLotSize:
-				sd.AmountDecimalPlaces = filter.StepSize.Exponent() * -1
-				sd.MinAmount, _ = filter.MinQty.Float64()
-			case FilterTypeMinNotional:
-				sd.MinTotal, _ = filter.MinNotional.Float64()
-			default:
-				// ignore
-			}
-		}
-		b.symbolDetailsMap[currencyPair.Display("/", false)] = &sd
+	b.marketsMtx.RLock()
+	exchangeProducts := make([]string, len(b.exchangeInfo.Symbols))
+	for i := range b.exchangeInfo.Symbols {
+		exchangeProducts[i] = b.exchangeInfo.Symbols[i].Symbol
 	}
+	b.marketsMtx.RUnlock()
 
 	err = b.UpdateAvailableCurrencies(exchangeProducts, false)
 	if err != nil {
@@ -149,7 +134,9 @@ func (b *Binance) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderb
 		})
 	}
 
-	b.Orderbooks.ProcessOrderbook(b.Name, p, book, assetType)
+	if err := b.Orderbooks.ProcessOrderbook(b.Name, p, book, assetType); err != nil {
+		return book, err
+	}
 	return b.Orderbooks.GetOrderbook(b.Name, p, assetType)
 }
 
@@ -0,0 +1,98 @@
+package binance
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Binance sends a ping frame every few minutes and drops the connection if it doesn't receive
+	// a pong in time, if nothing (not even a ping) is received within this timeout the connection
+	// is assumed to be dead.
+	wsReadTimeout = 10 * time.Minute
+	// Max time allowed for writing a control frame (e.g. a pong).
+	wsWriteTimeout = 10 * time.Second
+	// Number of received messages that can be queued up before reading from the connection blocks.
+	wsMessageBufferSize = 1000
+)
+
+var errWSConnClosed = errors.New("websocket connection closed")
+
+// wsConn wraps a websocket connection, it responds to the server's pings, treats the connection
+// as dead if nothing is received before the read deadline, and delivers received messages via a
+// channel that's closed when the connection is closed or fails.
+type wsConn struct {
+	conn        *websocket.Conn
+	readTimeout time.Duration
+	messages    chan []byte
+	// The reason the connection was disconnected, must not be accessed until messages is closed.
+	err       error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newWSConn(conn *websocket.Conn, readTimeout time.Duration) *wsConn {
+	c := &wsConn{
+		conn:        conn,
+		readTimeout: readTimeout,
+		messages:    make(chan []byte, wsMessageBufferSize),
+		done:        make(chan struct{}),
+	}
+	conn.SetPingHandler(c.handlePing)
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	go c.read()
+	return c
+}
+
+// handlePing extends the read deadline and responds to a ping from the server with a pong.
+func (c *wsConn) handlePing(data string) error {
+	c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	err := c.conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(wsWriteTimeout))
+	if err == websocket.ErrCloseSent {
+		return nil
+	}
+	if e, ok := err.(net.Error); ok && e.Temporary() {
+		return nil
+	}
+	return err
+}
+
+// read forwards messages from the connection until the connection is closed or fails.
+func (c *wsConn) read() {
+	defer close(c.messages)
+	for {
+		_, msg, err := c.conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-c.done:
+				c.err = errWSConnClosed
+			default:
+				c.err = err
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		select {
+		case c.messages <- msg:
+		case <-c.done:
+			c.err = errWSConnClosed
+			return
+		}
+	}
+}
+
+// Close closes the connection, the messages channel is closed shortly afterwards.
+func (c *wsConn) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return c.conn.Close()
+}
+
+// Err returns the reason the connection was disconnected, it must only be called after the
+// messages channel has been closed.
+func (c *wsConn) Err() error {
+	return c.err
+}
@@ -0,0 +1,65 @@
+package binance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWSConn(t *testing.T) {
+	t.Parallel()
+	pongs := make(chan string, 1)
+	server := newTestWebsocketServer(nil, func(stream string, conn *websocket.Conn) {
+		conn.SetPongHandler(func(data string) error {
+			pongs <- data
+			return nil
+		})
+		conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(time.Second))
+		conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+		// Keep reading so the pong is processed, but don't send anything else so the client's
+		// read deadline expires.
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	dialer := websocket.Dialer{}
+	conn, _, err := dialer.Dial(newTestWebsocketBinance(server).WebsocketURL+"test", nil)
+	if err != nil {
+		t.Fatalf("Test failed. Failed to connect: %s", err)
+	}
+	c := newWSConn(conn, 500*time.Millisecond)
+	defer c.Close()
+
+	select {
+	case msg := <-c.messages:
+		if string(msg) != "hello" {
+			t.Errorf("Test failed. Unexpected message %s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Timed out waiting for message")
+	}
+	select {
+	case data := <-pongs:
+		if data != "ping" {
+			t.Errorf("Test failed. Unexpected pong %s", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Timed out waiting for pong")
+	}
+	select {
+	case _, ok := <-c.messages:
+		if ok {
+			t.Fatal("Test failed. Received unexpected message")
+		}
+		if c.Err() == nil || c.Err() == errWSConnClosed {
+			t.Errorf("Test failed. Expected read deadline error, got %v", c.Err())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Timed out waiting for read deadline to expire")
+	}
+}
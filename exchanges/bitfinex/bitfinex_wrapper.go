@@ -111,7 +111,9 @@ func (b *Bitfinex) UpdateOrderbook(p pair.CurrencyPair, assetType string) (order
 		orderBook.Bids = append(orderBook.Bids, orderbook.Item{Price: orderbookNew.Bids[x].Price, Amount: orderbookNew.Bids[x].Amount})
 	}
 
-	b.Orderbooks.ProcessOrderbook(b.GetName(), p, orderBook, assetType)
+	if err := b.Orderbooks.ProcessOrderbook(b.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return b.Orderbooks.GetOrderbook(b.Name, p, assetType)
 }
 
@@ -83,7 +83,9 @@ func (b *Bitstamp) UpdateOrderbook(p pair.CurrencyPair, assetType string) (order
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: data.Amount, Price: data.Price})
 	}
 
-	b.Orderbooks.ProcessOrderbook(b.GetName(), p, orderBook, assetType)
+	if err := b.Orderbooks.ProcessOrderbook(b.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return b.Orderbooks.GetOrderbook(b.Name, p, assetType)
 }
 
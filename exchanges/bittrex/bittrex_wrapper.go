@@ -156,7 +156,9 @@ func (b *Bittrex) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderb
 		)
 	}
 
-	b.Orderbooks.ProcessOrderbook(b.GetName(), p, orderBook, assetType)
+	if err := b.Orderbooks.ProcessOrderbook(b.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return b.Orderbooks.GetOrderbook(b.Name, p, assetType)
 }
 
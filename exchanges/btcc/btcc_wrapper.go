@@ -82,7 +82,9 @@ func (b *BTCC) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderbook
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Price: data[0], Amount: data[1]})
 	}
 
-	b.Orderbooks.ProcessOrderbook(b.GetName(), p, orderBook, assetType)
+	if err := b.Orderbooks.ProcessOrderbook(b.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return b.Orderbooks.GetOrderbook(b.Name, p, assetType)
 }
 
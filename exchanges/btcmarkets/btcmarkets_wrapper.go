@@ -101,7 +101,9 @@ func (b *BTCMarkets) UpdateOrderbook(p pair.CurrencyPair, assetType string) (ord
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: data[1], Price: data[0]})
 	}
 
-	b.Orderbooks.ProcessOrderbook(b.GetName(), p, orderBook, assetType)
+	if err := b.Orderbooks.ProcessOrderbook(b.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return b.Orderbooks.GetOrderbook(b.Name, p, assetType)
 }
 
@@ -120,6 +120,8 @@ func (c *COINUT) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderbo
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: orderbookNew.Sell[x].Quantity, Price: orderbookNew.Sell[x].Price})
 	}
 
-	c.Orderbooks.ProcessOrderbook(c.GetName(), p, orderBook, assetType)
+	if err := c.Orderbooks.ProcessOrderbook(c.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return c.Orderbooks.GetOrderbook(c.Name, p, assetType)
 }
@@ -123,6 +123,8 @@ func (g *GDAX) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderbook
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: obNew.Bids[x].Amount, Price: obNew.Bids[x].Price})
 	}
 
-	g.Orderbooks.ProcessOrderbook(g.GetName(), p, orderBook, assetType)
+	if err := g.Orderbooks.ProcessOrderbook(g.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return g.Orderbooks.GetOrderbook(g.Name, p, assetType)
 }
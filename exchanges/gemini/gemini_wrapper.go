@@ -108,6 +108,8 @@ func (g *Gemini) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderbo
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: orderbookNew.Asks[x].Amount, Price: orderbookNew.Asks[x].Price})
 	}
 
-	g.Orderbooks.ProcessOrderbook("", p, orderBook, assetType)
+	if err := g.Orderbooks.ProcessOrderbook("", p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return g.Orderbooks.GetOrderbook(g.Name, p, assetType)
 }
@@ -82,7 +82,9 @@ func (h *HUOBI) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderboo
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: data[1], Price: data[0]})
 	}
 
-	h.Orderbooks.ProcessOrderbook(h.GetName(), p, orderBook, assetType)
+	if err := h.Orderbooks.ProcessOrderbook(h.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return h.Orderbooks.GetOrderbook(h.Name, p, assetType)
 }
 
@@ -96,7 +96,9 @@ func (i *ItBit) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderboo
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: amount, Price: price})
 	}
 
-	i.Orderbooks.ProcessOrderbook(i.GetName(), p, orderBook, assetType)
+	if err := i.Orderbooks.ProcessOrderbook(i.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return i.Orderbooks.GetOrderbook(i.Name, p, assetType)
 }
 
@@ -140,7 +140,9 @@ func (k *Kraken) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderbo
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: orderbookNew.Asks[x].Amount, Price: orderbookNew.Asks[x].Price})
 	}
 
-	k.Orderbooks.ProcessOrderbook(k.GetName(), p, orderBook, assetType)
+	if err := k.Orderbooks.ProcessOrderbook(k.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return k.Orderbooks.GetOrderbook(k.Name, p, assetType)
 }
 
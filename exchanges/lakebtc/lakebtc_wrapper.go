@@ -80,7 +80,9 @@ func (l *LakeBTC) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderb
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: orderbookNew.Asks[x].Amount, Price: orderbookNew.Asks[x].Price})
 	}
 
-	l.Orderbooks.ProcessOrderbook(l.GetName(), p, orderBook, assetType)
+	if err := l.Orderbooks.ProcessOrderbook(l.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return l.Orderbooks.GetOrderbook(l.Name, p, assetType)
 }
 
@@ -101,7 +101,9 @@ func (l *Liqui) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderboo
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: data[1], Price: data[0]})
 	}
 
-	l.Orderbooks.ProcessOrderbook(l.Name, p, orderBook, assetType)
+	if err := l.Orderbooks.ProcessOrderbook(l.Name, p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return l.Orderbooks.GetOrderbook(l.Name, p, assetType)
 }
 
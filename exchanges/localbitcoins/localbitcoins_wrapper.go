@@ -78,7 +78,9 @@ func (l *LocalBitcoins) UpdateOrderbook(p pair.CurrencyPair, assetType string) (
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: data.Amount, Price: data.Price})
 	}
 
-	l.Orderbooks.ProcessOrderbook(l.GetName(), p, orderBook, assetType)
+	if err := l.Orderbooks.ProcessOrderbook(l.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return l.Orderbooks.GetOrderbook(l.Name, p, assetType)
 }
 
@@ -100,7 +100,9 @@ func (o *OKCoin) UpdateOrderbook(currency pair.CurrencyPair, assetType string) (
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: data[1], Price: data[0]})
 	}
 
-	o.Orderbooks.ProcessOrderbook(o.GetName(), currency, orderBook, assetType)
+	if err := o.Orderbooks.ProcessOrderbook(o.GetName(), currency, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return o.Orderbooks.GetOrderbook(o.Name, currency, assetType)
 }
 
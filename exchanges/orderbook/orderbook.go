@@ -1,8 +1,12 @@
 package orderbook
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -14,8 +18,22 @@ const (
 	ErrOrderbookForExchangeNotFound = "Orderbook for exchange does not exist."
 	ErrPrimaryCurrencyNotFound      = "Error primary currency for orderbook not found."
 	ErrSecondaryCurrencyNotFound    = "Error secondary currency for orderbook not found."
+	ErrInvalidSide                  = "Invalid side, must be buy or sell."
+	ErrOrderbookSideEmpty           = "Orderbook side is empty."
+	ErrInsufficientDepth            = "Orderbook doesn't have sufficient depth."
+	ErrInvalidCurrencyPair          = "Orderbook currency pair is invalid."
+	ErrOrderbookEmpty               = "Orderbook has no bids or asks."
+	ErrOrderbookNotFound            = "Orderbook not found."
+	ErrMalformedLevel               = "Orderbook level must consist of a price and an amount."
 
 	Spot = "SPOT"
+
+	// Number of decimal places prices & amounts are encoded with in Binance depth payloads.
+	binanceDepthPrecision = 8
+
+	// Trade sides, a buy consumes the asks and a sell consumes the bids.
+	SideBuy  = "buy"
+	SideSell = "sell"
 )
 
 // CalculateTotalBids returns the total amount of bids and the total orderbook
@@ -42,17 +60,455 @@ func (o *Base) CalculateTotalAsks() (float64, float64) {
 	return amountCollated, total
 }
 
-// Update updates the bids and asks
-func (o *Base) Update(Bids, Asks []Item) {
-	o.Bids = Bids
-	o.Asks = Asks
+// TotalNotional returns the total value (the sum of price * amount) of the bids and of the asks.
+func (o *Base) TotalNotional() (bidNotional, askNotional float64) {
+	_, bidNotional = o.CalculateTotalBids()
+	_, askNotional = o.CalculateTotalAsks()
+	return bidNotional, askNotional
+}
+
+// AverageBidPrice returns the average price of all the bids weighted by amount, an error is
+// returned if there are no bids.
+func (o *Base) AverageBidPrice() (float64, error) {
+	return averagePrice(o.CalculateTotalBids())
+}
+
+// AverageAskPrice returns the average price of all the asks weighted by amount, an error is
+// returned if there are no asks.
+func (o *Base) AverageAskPrice() (float64, error) {
+	return averagePrice(o.CalculateTotalAsks())
+}
+
+func averagePrice(amount, total float64) (float64, error) {
+	if amount == 0 {
+		return 0, errors.New(ErrOrderbookSideEmpty)
+	}
+	return total / amount, nil
+}
+
+// CumulativeBids returns the bids with the amount of each level set to the total amount of all
+// the levels from the best bid up to and including that level.
+func (o *Base) CumulativeBids() []Item {
+	return cumulativeLevels(o.Bids)
+}
+
+// CumulativeAsks returns the asks with the amount of each level set to the total amount of all
+// the levels from the best ask up to and including that level.
+func (o *Base) CumulativeAsks() []Item {
+	return cumulativeLevels(o.Asks)
+}
+
+func cumulativeLevels(levels []Item) []Item {
+	result := make([]Item, len(levels))
+	total := float64(0)
+	for i, x := range levels {
+		total += x.Amount
+		result[i] = Item{Price: x.Price, Amount: total}
+	}
+	return result
+}
+
+// VolumeToMovePrice returns the volume that has to be bought (consuming the asks) or sold
+// (consuming the bids) to move the price pct percent away from the best price, along with the
+// price of the level that becomes the best price once that volume has been consumed.
+func (o *Base) VolumeToMovePrice(side string, pct float64) (volume float64, reachedPrice float64, err error) {
+	levels, err := o.takerLevels(side)
+	if err != nil {
+		return 0, 0, err
+	}
+	if pct <= 0 {
+		return 0, 0, errors.New("pct must be greater than zero")
+	}
+	if len(levels) == 0 {
+		return 0, 0, errors.New(ErrOrderbookSideEmpty)
+	}
+
+	targetPrice := levels[0].Price * (1 + pct/100)
+	if side == SideSell {
+		targetPrice = levels[0].Price * (1 - pct/100)
+	}
+	for _, level := range levels {
+		if (side == SideBuy && level.Price >= targetPrice) ||
+			(side == SideSell && level.Price <= targetPrice) {
+			return volume, level.Price, nil
+		}
+		volume += level.Amount
+	}
+	return volume, levels[len(levels)-1].Price, errors.New(ErrInsufficientDepth)
+}
+
+// PriceAtCumulativeVolume returns the price of the level at which the total amount of the levels
+// a buy (consuming the asks) or a sell (consuming the bids) walks through reaches the given
+// volume. Returns false if the side is invalid or doesn't have enough depth.
+func (o *Base) PriceAtCumulativeVolume(side string, volume float64) (price float64, ok bool) {
+	levels, err := o.takerLevels(side)
+	if err != nil {
+		return 0, false
+	}
+	total := float64(0)
+	for _, level := range levels {
+		total += level.Amount
+		if total >= volume {
+			return level.Price, true
+		}
+	}
+	return 0, false
+}
+
+// CanFillAtPrice checks if a buy (consuming the asks) or a sell (consuming the bids) of the given
+// quantity could be filled entirely at the given limit price or better, e.g. to avoid sending a
+// FOK order that's bound to be killed. Returns false if the side is invalid.
+func (o *Base) CanFillAtPrice(side string, quantity float64, limitPrice float64) bool {
+	levels, err := o.takerLevels(side)
+	if err != nil {
+		return false
+	}
+	available := float64(0)
+	for _, level := range levels {
+		if (side == SideBuy && level.Price > limitPrice) || (side == SideSell && level.Price < limitPrice) {
+			break
+		}
+		available += level.Amount
+		if available >= quantity {
+			return true
+		}
+	}
+	return false
+}
+
+// NotionalToBaseBuy returns the base amount that can be bought by spending the given notional
+// amount of the quote currency on the asks, along with the average price paid.
+func (o *Base) NotionalToBaseBuy(notional float64) (baseQty float64, avgPrice float64, err error) {
+	return notionalToBase(o.Asks, notional)
+}
+
+// NotionalToBaseSell returns the base amount that has to be sold into the bids to receive the
+// given notional amount of the quote currency, along with the average price received.
+func (o *Base) NotionalToBaseSell(notional float64) (baseQty float64, avgPrice float64, err error) {
+	return notionalToBase(o.Bids, notional)
+}
+
+// notionalToBase walks the given levels (best price first) until the given notional amount has
+// been traded. If the levels don't have enough depth the totals for all the levels are returned
+// along with an error.
+func notionalToBase(levels []Item, notional float64) (baseQty float64, avgPrice float64, err error) {
+	if notional <= 0 {
+		return 0, 0, errors.New("notional must be greater than zero")
+	}
+	if len(levels) == 0 {
+		return 0, 0, errors.New(ErrOrderbookSideEmpty)
+	}
+	remaining := notional
+	for _, level := range levels {
+		levelNotional := level.Price * level.Amount
+		if levelNotional >= remaining {
+			baseQty += remaining / level.Price
+			return baseQty, notional / baseQty, nil
+		}
+		baseQty += level.Amount
+		remaining -= levelNotional
+	}
+	return baseQty, (notional - remaining) / baseQty, errors.New(ErrInsufficientDepth)
+}
+
+// VWAPBuy returns the volume weighted average price paid when buying the given base amount from the
+// asks.
+func (o *Base) VWAPBuy(size float64) (float64, error) {
+	return vwap(o.Asks, size)
+}
+
+// VWAPSell returns the volume weighted average price received when selling the given base amount
+// into the bids.
+func (o *Base) VWAPSell(size float64) (float64, error) {
+	return vwap(o.Bids, size)
+}
+
+// EffectiveSpread returns the difference between the average prices of buying and selling the
+// given base amount, i.e. the round-trip cost per unit of trading that size, as opposed to the
+// quoted spread between the best bid & ask.
+func (o *Base) EffectiveSpread(size float64) (float64, error) {
+	buyPrice, err := o.VWAPBuy(size)
+	if err != nil {
+		return 0, err
+	}
+	sellPrice, err := o.VWAPSell(size)
+	if err != nil {
+		return 0, err
+	}
+	return buyPrice - sellPrice, nil
+}
+
+// vwap walks the given levels (best price first) until the given base amount has been traded and
+// returns the average price, or an error if the levels don't have enough depth.
+func vwap(levels []Item, size float64) (float64, error) {
+	if size <= 0 {
+		return 0, errors.New("size must be greater than zero")
+	}
+	if len(levels) == 0 {
+		return 0, errors.New(ErrOrderbookSideEmpty)
+	}
+	remaining := size
+	total := float64(0)
+	for _, level := range levels {
+		if level.Amount >= remaining {
+			total += remaining * level.Price
+			return total / size, nil
+		}
+		total += level.Amount * level.Price
+		remaining -= level.Amount
+	}
+	return 0, errors.New(ErrInsufficientDepth)
+}
+
+// TopOfBook returns the price & amount of the best bid and best ask without copying the book, ok is
+// false if either side is empty.
+func (o *Base) TopOfBook() (bidPrice, bidQty, askPrice, askQty float64, ok bool) {
+	if len(o.Bids) == 0 || len(o.Asks) == 0 {
+		return 0, 0, 0, 0, false
+	}
+	return o.Bids[0].Price, o.Bids[0].Amount, o.Asks[0].Price, o.Asks[0].Amount, true
+}
+
+// SlippageVsMid returns the difference between the given fill price of a buy or sell and the
+// mid price (halfway between the best bid & ask) in basis points of the mid price. The result is
+// positive when the fill price is worse than the mid price for the given side, i.e. when a buy is
+// filled above the mid price or a sell below it.
+func (o *Base) SlippageVsMid(side string, fillPrice float64) (float64, error) {
+	if _, err := o.takerLevels(side); err != nil {
+		return 0, err
+	}
+	if len(o.Bids) == 0 || len(o.Asks) == 0 {
+		return 0, errors.New(ErrOrderbookSideEmpty)
+	}
+	mid := (o.Bids[0].Price + o.Asks[0].Price) / 2
+	slippage := (fillPrice - mid) / mid * 10000
+	if side == SideSell {
+		slippage = -slippage
+	}
+	return slippage, nil
+}
+
+// LevelsWithDistance returns the bids and asks annotated with their distance from the mid price
+// (halfway between the best bid & ask) as a percentage of the mid price, e.g. for depth charts.
+// Returns an error if the orderbook is empty, or if either side is empty as the mid price is
+// undefined.
+func (o *Base) LevelsWithDistance() (bids, asks []LevelDistance, err error) {
+	if len(o.Bids) == 0 && len(o.Asks) == 0 {
+		return nil, nil, errors.New(ErrOrderbookEmpty)
+	}
+	if len(o.Bids) == 0 || len(o.Asks) == 0 {
+		return nil, nil, errors.New(ErrOrderbookSideEmpty)
+	}
+	mid := (o.Bids[0].Price + o.Asks[0].Price) / 2
+	return levelDistances(o.Bids, mid), levelDistances(o.Asks, mid), nil
+}
+
+func levelDistances(levels []Item, mid float64) []LevelDistance {
+	distances := make([]LevelDistance, len(levels))
+	for i, level := range levels {
+		distances[i] = LevelDistance{
+			Price:          level.Price,
+			Amount:         level.Amount,
+			PercentFromMid: math.Abs(level.Price-mid) / mid * 100,
+		}
+	}
+	return distances
+}
+
+// takerLevels returns the levels a trade on the given side would consume, best price first.
+func (o *Base) takerLevels(side string) ([]Item, error) {
+	switch side {
+	case SideBuy:
+		return o.Asks, nil
+	case SideSell:
+		return o.Bids, nil
+	}
+	return nil, errors.New(ErrInvalidSide)
+}
+
+// ApplyDelta applies incremental updates to the bids and asks, each update replaces the amount of
+// the level at the update price, or removes the level if the update amount is zero. Bids are kept
+// sorted by descending price and asks by ascending price.
+func (o *Base) ApplyDelta(bids, asks []Item) {
+	for _, update := range bids {
+		o.Bids = applyLevelUpdate(o.Bids, update, true)
+	}
+	for _, update := range asks {
+		o.Asks = applyLevelUpdate(o.Asks, update, false)
+	}
 	o.LastUpdated = time.Now()
 }
 
+func applyLevelUpdate(levels []Item, update Item, descending bool) []Item {
+	i := sort.Search(len(levels), func(i int) bool {
+		if descending {
+			return levels[i].Price <= update.Price
+		}
+		return levels[i].Price >= update.Price
+	})
+	if i < len(levels) && levels[i].Price == update.Price {
+		if update.Amount == 0 {
+			return append(levels[:i], levels[i+1:]...)
+		}
+		levels[i].Amount = update.Amount
+		return levels
+	}
+	if update.Amount == 0 {
+		return levels
+	}
+	levels = append(levels, Item{})
+	copy(levels[i+1:], levels[i:])
+	levels[i] = update
+	return levels
+}
+
+// Compact removes any levels with a non-positive amount from the bids and asks, and restores the
+// sort order (bids by descending price, asks by ascending price) if it has been broken, e.g. after
+// the levels have been modified directly. Orderbooks stored via ProcessOrderbook don't need to be
+// compacted as invalid levels are dropped before they're stored.
+func (o *Base) Compact() {
+	o.Bids = compactLevels(o.Bids, func(a, b Item) bool { return a.Price > b.Price })
+	o.Asks = compactLevels(o.Asks, func(a, b Item) bool { return a.Price < b.Price })
+}
+
+// compactLevels removes the levels with a non-positive amount in place, and sorts the remaining
+// levels using the given ordering if they aren't already sorted.
+func compactLevels(levels []Item, less func(a, b Item) bool) []Item {
+	compacted := levels[:0]
+	for _, level := range levels {
+		if level.Amount > 0 {
+			compacted = append(compacted, level)
+		}
+	}
+	isLess := func(i, j int) bool { return less(compacted[i], compacted[j]) }
+	if !sort.SliceIsSorted(compacted, isLess) {
+		sort.SliceStable(compacted, isLess)
+	}
+	return compacted
+}
+
+// Update updates the bids and asks, levels with a non-positive price or amount are dropped.
+// Returns the number of levels that were dropped.
+func (o *Base) Update(Bids, Asks []Item) (dropped int) {
+	o.Bids, dropped = sanitizeLevels(Bids)
+	var droppedAsks int
+	o.Asks, droppedAsks = sanitizeLevels(Asks)
+	o.LastUpdated = time.Now()
+	return dropped + droppedAsks
+}
+
+// sanitizeLevels returns the given levels without any levels that have a non-positive price or
+// amount, along with the number of levels that were dropped. The given slice is returned as is if
+// none of the levels need to be dropped, otherwise a new slice is returned.
+func sanitizeLevels(levels []Item) ([]Item, int) {
+	dropped := 0
+	for _, level := range levels {
+		if level.Price <= 0 || level.Amount <= 0 {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		return levels, 0
+	}
+	valid := make([]Item, 0, len(levels)-dropped)
+	for _, level := range levels {
+		if level.Price > 0 && level.Amount > 0 {
+			valid = append(valid, level)
+		}
+	}
+	return valid, dropped
+}
+
+// GetBids returns the bids, if copy is true a fresh slice is returned that can be modified (or
+// read while the orderbook is being updated) without affecting the orderbook, otherwise the
+// returned slice shares its storage with the orderbook.
+func (o *Base) GetBids(copy bool) []Item {
+	return levelsSlice(o.Bids, copy)
+}
+
+// GetAsks returns the asks, see GetBids for the meaning of the copy parameter.
+func (o *Base) GetAsks(copy bool) []Item {
+	return levelsSlice(o.Asks, copy)
+}
+
+func levelsSlice(levels []Item, copy bool) []Item {
+	if !copy || levels == nil {
+		return levels
+	}
+	return append(make([]Item, 0, len(levels)), levels...)
+}
+
+// Clone returns a deep copy of the orderbook, the copy can be modified without affecting the
+// original.
+func (o Base) Clone() Base {
+	clone := o
+	if o.Bids != nil {
+		clone.Bids = append([]Item(nil), o.Bids...)
+	}
+	if o.Asks != nil {
+		clone.Asks = append([]Item(nil), o.Asks...)
+	}
+	return clone
+}
+
+// ReplaceFrom replaces the bids and asks with copies of those in the given snapshot, and sets
+// LastUpdated to the time of the snapshot (or the current time if the snapshot has no time).
+func (o *Base) ReplaceFrom(snapshot Base) {
+	o.Bids = append([]Item(nil), snapshot.Bids...)
+	o.Asks = append([]Item(nil), snapshot.Asks...)
+	o.LastUpdated = snapshot.LastUpdated
+	if o.LastUpdated.IsZero() {
+		o.LastUpdated = time.Now()
+	}
+}
+
 // Stores the order books, and provides helper methods
 type Orderbooks struct {
-	m          sync.Mutex
+	m          sync.RWMutex
 	orderbooks map[pair.CurrencyItem]map[pair.CurrencyItem]map[string]Base
+	// Number of snapshots retained per orderbook, zero when history is disabled.
+	historyDepth int
+	history      map[historyKey]*snapshotRing
+	// Length of the window over which update rates are measured, zero when disabled.
+	updateRateWindow time.Duration
+	updateTimes      map[historyKey][]time.Time
+	// Optional handler notified of the invalid levels ProcessOrderbook drops.
+	onDroppedLevels DroppedLevelsHandler
+}
+
+// DroppedLevelsHandler is notified of the number of invalid bids and asks dropped from an
+// orderbook passed to ProcessOrderbook, see SetDroppedLevelsHandler.
+type DroppedLevelsHandler func(exchangeName string, p pair.CurrencyPair, orderbookType string,
+	droppedBids, droppedAsks int)
+
+type historyKey struct {
+	first, second pair.CurrencyItem
+	orderbookType string
+}
+
+// snapshotRing retains the most recent orderbook snapshots, overwriting the oldest snapshot once
+// it's full.
+type snapshotRing struct {
+	snapshots []Base
+	next      int
+}
+
+func (r *snapshotRing) add(snapshot Base, depth int) {
+	if len(r.snapshots) < depth {
+		r.snapshots = append(r.snapshots, snapshot)
+		return
+	}
+	r.snapshots[r.next] = snapshot
+	r.next = (r.next + 1) % len(r.snapshots)
+}
+
+// ordered returns copies of the snapshots, oldest first.
+func (r *snapshotRing) ordered() []Base {
+	ordered := make([]Base, 0, len(r.snapshots))
+	for i := range r.snapshots {
+		ordered = append(ordered, r.snapshots[(r.next+i)%len(r.snapshots)].Clone())
+	}
+	return ordered
 }
 
 // Item stores the amount and price values
@@ -61,6 +517,14 @@ type Item struct {
 	Price  float64
 }
 
+// LevelDistance is an orderbook level annotated with its distance from the mid price, as a
+// (non-negative) percentage of the mid price.
+type LevelDistance struct {
+	Price          float64
+	Amount         float64
+	PercentFromMid float64
+}
+
 // Base holds the fields for the orderbook base
 type Base struct {
 	Pair         pair.CurrencyPair `json:"pair"`
@@ -70,11 +534,138 @@ type Base struct {
 	LastUpdated  time.Time         `json:"last_updated"`
 }
 
-// GetOrderbook checks and returns the orderbook given an exchange name and
+// Depth is a lightweight container for the bids and asks of an orderbook.
+type Depth struct {
+	Bids []Item
+	Asks []Item
+}
+
+// ToBase returns an orderbook for the given currency pair containing copies of the bids and asks.
+func (d Depth) ToBase(p pair.CurrencyPair) Base {
+	return Base{
+		Pair:         p,
+		CurrencyPair: p.Pair().String(),
+		Bids:         append([]Item(nil), d.Bids...),
+		Asks:         append([]Item(nil), d.Asks...),
+	}
+}
+
+// NewBaseFromTuples returns an orderbook for the given currency pair built from price & amount
+// tuples, the bids are sorted by descending price and the asks by ascending price. Returns an error
+// if any of the tuples doesn't consist of exactly two values.
+func NewBaseFromTuples(p pair.CurrencyPair, bids, asks [][]float64) (Base, error) {
+	bidLevels, err := levelsFromTuples(bids)
+	if err != nil {
+		return Base{}, err
+	}
+	askLevels, err := levelsFromTuples(asks)
+	if err != nil {
+		return Base{}, err
+	}
+	sort.SliceStable(bidLevels, func(i, j int) bool { return bidLevels[i].Price > bidLevels[j].Price })
+	sort.SliceStable(askLevels, func(i, j int) bool { return askLevels[i].Price < askLevels[j].Price })
+	return Base{
+		Pair:         p,
+		CurrencyPair: p.Pair().String(),
+		Bids:         bidLevels,
+		Asks:         askLevels,
+	}, nil
+}
+
+func levelsFromTuples(tuples [][]float64) ([]Item, error) {
+	levels := make([]Item, len(tuples))
+	for i, tuple := range tuples {
+		if len(tuple) != 2 {
+			return nil, fmt.Errorf("%s Got %d values at index %d.", ErrMalformedLevel, len(tuple), i)
+		}
+		levels[i] = Item{Price: tuple[0], Amount: tuple[1]}
+	}
+	return levels, nil
+}
+
+// ToDepth returns copies of the bids and asks of the orderbook.
+func (o Base) ToDepth() Depth {
+	return Depth{
+		Bids: append([]Item(nil), o.Bids...),
+		Asks: append([]Item(nil), o.Asks...),
+	}
+}
+
+// binanceDepth is the payload of the Binance depth endpoint, levels are encoded as
+// ["price", "amount"] string tuples.
+type binanceDepth struct {
+	Bids [][]string `json:"bids"`
+	Asks [][]string `json:"asks"`
+}
+
+// MarshalBinanceDepth encodes the bids and asks in the format of the Binance depth endpoint, i.e.
+// {"bids":[["price","amount"],...],"asks":[...]} with the prices & amounts encoded as strings with
+// 8 decimal places.
+func (o *Base) MarshalBinanceDepth() ([]byte, error) {
+	return json.Marshal(binanceDepth{
+		Bids: binanceDepthLevels(o.Bids),
+		Asks: binanceDepthLevels(o.Asks),
+	})
+}
+
+func binanceDepthLevels(levels []Item) [][]string {
+	tuples := make([][]string, len(levels))
+	for i, level := range levels {
+		tuples[i] = []string{
+			strconv.FormatFloat(level.Price, 'f', binanceDepthPrecision, 64),
+			strconv.FormatFloat(level.Amount, 'f', binanceDepthPrecision, 64),
+		}
+	}
+	return tuples
+}
+
+// UnmarshalBinanceDepth replaces the bids and asks with those decoded from a payload in the format
+// of the Binance depth endpoint (see MarshalBinanceDepth), the bids are sorted by descending price
+// and the asks by ascending price. The orderbook is left unchanged if the payload is malformed.
+func (o *Base) UnmarshalBinanceDepth(data []byte) error {
+	depth := binanceDepth{}
+	if err := json.Unmarshal(data, &depth); err != nil {
+		return err
+	}
+	bids, err := levelsFromBinanceDepth(depth.Bids)
+	if err != nil {
+		return err
+	}
+	asks, err := levelsFromBinanceDepth(depth.Asks)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(bids, func(i, j int) bool { return bids[i].Price > bids[j].Price })
+	sort.SliceStable(asks, func(i, j int) bool { return asks[i].Price < asks[j].Price })
+	o.Bids = bids
+	o.Asks = asks
+	return nil
+}
+
+func levelsFromBinanceDepth(tuples [][]string) ([]Item, error) {
+	levels := make([]Item, len(tuples))
+	for i, tuple := range tuples {
+		if len(tuple) != 2 {
+			return nil, fmt.Errorf("%s Got %d values at index %d.", ErrMalformedLevel, len(tuple), i)
+		}
+		price, err := strconv.ParseFloat(tuple[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		amount, err := strconv.ParseFloat(tuple[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		levels[i] = Item{Price: price, Amount: amount}
+	}
+	return levels, nil
+}
+
+// GetOrderbook checks and returns a copy of the orderbook given an exchange name and
 // currency pair if it exists
 func (o *Orderbooks) GetOrderbook(_ string, p pair.CurrencyPair, orderbookType string) (Base, error) {
-	o.m.Lock()
-	defer o.m.Unlock()
+	o.m.RLock()
+	defer o.m.RUnlock()
 
 	fp := o.formatCurrencyPair(p)
 
@@ -87,7 +678,202 @@ func (o *Orderbooks) GetOrderbook(_ string, p pair.CurrencyPair, orderbookType s
 		return Base{}, err
 	}
 
-	return o.orderbooks[fp.GetFirstCurrency()][fp.GetSecondCurrency()][orderbookType], nil
+	return o.orderbooks[fp.GetFirstCurrency()][fp.GetSecondCurrency()][orderbookType].Clone(), nil
+}
+
+// LookupOrderbook returns a copy of the orderbook for the given currency pair and orderbook type,
+// the second return value indicates whether the orderbook was found.
+func (o *Orderbooks) LookupOrderbook(p pair.CurrencyPair, orderbookType string) (Base, bool) {
+	o.m.RLock()
+	defer o.m.RUnlock()
+
+	fp := o.formatCurrencyPair(p)
+	book, ok := o.orderbooks[fp.FirstCurrency][fp.SecondCurrency][orderbookType]
+	if !ok {
+		return Base{}, false
+	}
+	return book.Clone(), true
+}
+
+// BestAcrossTypes returns the best bid & ask across the orderbooks of the given types for the given
+// currency pair, types that don't have an orderbook for the pair are skipped. If none of the
+// orderbooks have any bids (or asks) the returned best bid (or ask) is the zero Item, an error is
+// returned if none of the types have an orderbook, or if the orderbooks are all empty.
+func (o *Orderbooks) BestAcrossTypes(p pair.CurrencyPair, types []string) (bestBid Item, bestAsk Item, err error) {
+	o.m.RLock()
+	defer o.m.RUnlock()
+
+	fp := o.formatCurrencyPair(p)
+	books := o.orderbooks[fp.FirstCurrency][fp.SecondCurrency]
+	found, hasBid, hasAsk := false, false, false
+	for _, orderbookType := range types {
+		book, ok := books[orderbookType]
+		if !ok {
+			continue
+		}
+		found = true
+		if len(book.Bids) > 0 && (!hasBid || book.Bids[0].Price > bestBid.Price) {
+			bestBid, hasBid = book.Bids[0], true
+		}
+		if len(book.Asks) > 0 && (!hasAsk || book.Asks[0].Price < bestAsk.Price) {
+			bestAsk, hasAsk = book.Asks[0], true
+		}
+	}
+	if !found {
+		return Item{}, Item{}, errors.New(ErrOrderbookNotFound)
+	}
+	if !hasBid && !hasAsk {
+		return Item{}, Item{}, errors.New(ErrOrderbookEmpty)
+	}
+	return bestBid, bestAsk, nil
+}
+
+// BestFillAcrossTypes returns the average price of a buy (consuming the asks) or a sell (consuming
+// the bids) of the given quantity when the cheapest liquidity across the orderbooks of the given
+// types for the given currency pair is consumed first, along with the quantity filled from each
+// orderbook type. Types that don't have an orderbook for the pair are skipped, an error is
+// returned if none of the types have an orderbook. If the orderbooks don't have enough depth the
+// totals for all the levels are returned along with an error.
+func (o *Orderbooks) BestFillAcrossTypes(p pair.CurrencyPair, side string, quantity float64, types []string) (avgPrice float64, perBook map[string]float64, err error) {
+	if side != SideBuy && side != SideSell {
+		return 0, nil, errors.New(ErrInvalidSide)
+	}
+	if quantity <= 0 {
+		return 0, nil, errors.New("quantity must be greater than zero")
+	}
+
+	type typedLevel struct {
+		orderbookType string
+		Item
+	}
+	var levels []typedLevel
+	found := false
+	func() {
+		o.m.RLock()
+		defer o.m.RUnlock()
+
+		fp := o.formatCurrencyPair(p)
+		books := o.orderbooks[fp.FirstCurrency][fp.SecondCurrency]
+		for _, orderbookType := range types {
+			book, ok := books[orderbookType]
+			if !ok {
+				continue
+			}
+			found = true
+			bookLevels, _ := book.takerLevels(side)
+			for _, level := range bookLevels {
+				levels = append(levels, typedLevel{orderbookType, level})
+			}
+		}
+	}()
+	if !found {
+		return 0, nil, errors.New(ErrOrderbookNotFound)
+	}
+	if len(levels) == 0 {
+		return 0, nil, errors.New(ErrOrderbookSideEmpty)
+	}
+
+	// Levels at the same price are consumed in the order of the given types.
+	sort.SliceStable(levels, func(i, j int) bool {
+		if side == SideBuy {
+			return levels[i].Price < levels[j].Price
+		}
+		return levels[i].Price > levels[j].Price
+	})
+	perBook = make(map[string]float64)
+	filled, total := float64(0), float64(0)
+	for _, level := range levels {
+		amount := math.Min(level.Amount, quantity-filled)
+		perBook[level.orderbookType] += amount
+		filled += amount
+		total += amount * level.Price
+		if filled >= quantity {
+			return total / filled, perBook, nil
+		}
+	}
+	return total / filled, perBook, errors.New(ErrInsufficientDepth)
+}
+
+// TypedOrderbook is an orderbook along with its orderbook type.
+type TypedOrderbook struct {
+	Type string
+	Base
+}
+
+// GetTrackedPairs returns the currency pairs that have at least one orderbook in the store, sorted
+// by their (internally formatted) pair string so the result is deterministic.
+func (o *Orderbooks) GetTrackedPairs() []pair.CurrencyPair {
+	o.m.RLock()
+	defer o.m.RUnlock()
+
+	pairs := []pair.CurrencyPair{}
+	for first, seconds := range o.orderbooks {
+		for second, books := range seconds {
+			if len(books) > 0 {
+				pairs = append(pairs, pair.CurrencyPair{Delimiter: "/", FirstCurrency: first, SecondCurrency: second})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Pair() < pairs[j].Pair() })
+	return pairs
+}
+
+// GetAllOrderbooks returns copies of all the orderbooks in the store, sorted by their (internally
+// formatted) pair string and then by orderbook type so the result is deterministic.
+func (o *Orderbooks) GetAllOrderbooks() []TypedOrderbook {
+	o.m.RLock()
+	defer o.m.RUnlock()
+
+	all := []TypedOrderbook{}
+	for _, seconds := range o.orderbooks {
+		for _, books := range seconds {
+			for orderbookType, book := range books {
+				all = append(all, TypedOrderbook{Type: orderbookType, Base: book.Clone()})
+			}
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CurrencyPair != all[j].CurrencyPair {
+			return all[i].CurrencyPair < all[j].CurrencyPair
+		}
+		return all[i].Type < all[j].Type
+	})
+	return all
+}
+
+// OldestUpdate returns the currency pair & last update time of the least recently updated
+// orderbook, or zero values if there are no orderbooks.
+func (o *Orderbooks) OldestUpdate() (pair.CurrencyPair, time.Time) {
+	return o.findUpdate(func(t, best time.Time) bool { return t.Before(best) })
+}
+
+// NewestUpdate returns the currency pair & last update time of the most recently updated
+// orderbook, or zero values if there are no orderbooks.
+func (o *Orderbooks) NewestUpdate() (pair.CurrencyPair, time.Time) {
+	return o.findUpdate(func(t, best time.Time) bool { return t.After(best) })
+}
+
+// findUpdate returns the currency pair & last update time of the orderbook whose last update
+// time is preferred over all others by the given comparison function.
+func (o *Orderbooks) findUpdate(better func(t, best time.Time) bool) (pair.CurrencyPair, time.Time) {
+	o.m.RLock()
+	defer o.m.RUnlock()
+
+	var bestPair pair.CurrencyPair
+	var bestTime time.Time
+	found := false
+	for first, seconds := range o.orderbooks {
+		for second, books := range seconds {
+			for _, book := range books {
+				if !found || better(book.LastUpdated, bestTime) {
+					found = true
+					bestPair = pair.CurrencyPair{Delimiter: "/", FirstCurrency: first, SecondCurrency: second}
+					bestTime = book.LastUpdated
+				}
+			}
+		}
+	}
+	return bestPair, bestTime
 }
 
 // FirstCurrencyExists checks to see if the first currency of the orderbook map
@@ -113,8 +899,31 @@ func (o *Orderbooks) SecondCurrencyExists(p pair.CurrencyPair) bool {
 }
 
 // ProcessOrderbook processes incoming orderbooks, creating or updating the
-// Orderbook list
-func (o *Orderbooks) ProcessOrderbook(_ string, p pair.CurrencyPair, orderbookNew Base, orderbookType string) {
+// Orderbook list. Levels with a non-positive price or amount are dropped (and reported to the
+// handler set by SetDroppedLevelsHandler) before the orderbook is stored, a snapshot is also
+// retained if history is enabled (see SetHistoryDepth), and the update is counted if update rate
+// tracking is enabled (see SetUpdateRateWindow).
+// Returns an error without storing the orderbook if either currency of the
+// currency pair is empty, or the orderbook has neither bids nor asks.
+func (o *Orderbooks) ProcessOrderbook(exchangeName string, p pair.CurrencyPair, orderbookNew Base, orderbookType string) error {
+	if p.FirstCurrency == "" || p.SecondCurrency == "" {
+		return errors.New(ErrInvalidCurrencyPair)
+	}
+	var droppedBids, droppedAsks int
+	orderbookNew.Bids, droppedBids = sanitizeLevels(orderbookNew.Bids)
+	orderbookNew.Asks, droppedAsks = sanitizeLevels(orderbookNew.Asks)
+	if droppedBids+droppedAsks > 0 {
+		o.m.RLock()
+		onDroppedLevels := o.onDroppedLevels
+		o.m.RUnlock()
+		if onDroppedLevels != nil {
+			onDroppedLevels(exchangeName, p, orderbookType, droppedBids, droppedAsks)
+		}
+	}
+	if len(orderbookNew.Bids) == 0 && len(orderbookNew.Asks) == 0 {
+		return errors.New(ErrOrderbookEmpty)
+	}
+
 	o.m.Lock()
 	defer o.m.Unlock()
 
@@ -123,16 +932,18 @@ func (o *Orderbooks) ProcessOrderbook(_ string, p pair.CurrencyPair, orderbookNe
 
 	orderbookNew.CurrencyPair = fp.Pair().String()
 	orderbookNew.LastUpdated = time.Now()
+	o.recordHistory(fp, orderbookType, orderbookNew)
+	o.recordUpdateTime(fp, orderbookType, orderbookNew.LastUpdated)
 
 	if o.FirstCurrencyExists(fp.GetFirstCurrency()) {
 		if !o.SecondCurrencyExists(fp) {
 			b := make(map[string]Base)
 			b[orderbookType] = orderbookNew
 			o.orderbooks[fp.FirstCurrency][fp.SecondCurrency] = b
-			return
+			return nil
 		} else {
 			o.orderbooks[fp.FirstCurrency][fp.SecondCurrency][orderbookType] = orderbookNew
-			return
+			return nil
 		}
 	}
 
@@ -141,6 +952,112 @@ func (o *Orderbooks) ProcessOrderbook(_ string, p pair.CurrencyPair, orderbookNe
 	b[orderbookType] = orderbookNew
 	a[fp.SecondCurrency] = b
 	o.orderbooks[fp.FirstCurrency] = a
+	return nil
+}
+
+// SetHistoryDepth sets the number of snapshots ProcessOrderbook retains per orderbook, so the
+// snapshots can later be retrieved with GetHistory. History is disabled by default, a depth of
+// zero (or less) disables it again and discards any retained snapshots. Changing the depth
+// discards the snapshots retained so far.
+func (o *Orderbooks) SetHistoryDepth(depth int) {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	if depth < 0 {
+		depth = 0
+	}
+	o.historyDepth = depth
+	o.history = nil
+	if depth > 0 {
+		o.history = make(map[historyKey]*snapshotRing)
+	}
+}
+
+// GetHistory returns copies of the retained snapshots of the orderbook for the given currency
+// pair and orderbook type, oldest first. Each snapshot's LastUpdated is the time it was processed.
+// Returns nil if history is disabled or no snapshots have been retained.
+func (o *Orderbooks) GetHistory(p pair.CurrencyPair, orderbookType string) []Base {
+	o.m.RLock()
+	defer o.m.RUnlock()
+
+	fp := o.formatCurrencyPair(p)
+	ring, ok := o.history[historyKey{fp.FirstCurrency, fp.SecondCurrency, orderbookType}]
+	if !ok {
+		return nil
+	}
+	return ring.ordered()
+}
+
+// SetDroppedLevelsHandler sets a handler that ProcessOrderbook notifies whenever it drops invalid
+// levels from an orderbook, e.g. to log feeds that send zero-quantity levels. Dropped levels aren't
+// reported by default, a nil handler disables reporting again.
+func (o *Orderbooks) SetDroppedLevelsHandler(handler DroppedLevelsHandler) {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	o.onDroppedLevels = handler
+}
+
+// SetUpdateRateWindow enables tracking of the rate at which each orderbook is updated via
+// ProcessOrderbook, measured over a rolling window of the given length, see UpdateRate. Tracking
+// is disabled by default, a window of zero (or less) disables it again. Changing the window
+// discards the updates tracked so far.
+func (o *Orderbooks) SetUpdateRateWindow(window time.Duration) {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	if window < 0 {
+		window = 0
+	}
+	o.updateRateWindow = window
+	o.updateTimes = nil
+	if window > 0 {
+		o.updateTimes = make(map[historyKey][]time.Time)
+	}
+}
+
+// UpdateRate returns the number of updates per second the orderbook for the given currency pair
+// and orderbook type received within the last update rate window (see SetUpdateRateWindow), zero
+// if tracking is disabled.
+func (o *Orderbooks) UpdateRate(p pair.CurrencyPair, orderbookType string) (updatesPerSecond float64) {
+	o.m.RLock()
+	defer o.m.RUnlock()
+
+	if o.updateRateWindow <= 0 {
+		return 0
+	}
+	fp := o.formatCurrencyPair(p)
+	times := o.updateTimes[historyKey{fp.FirstCurrency, fp.SecondCurrency, orderbookType}]
+	cutoff := time.Now().Add(-o.updateRateWindow)
+	count := len(times) - sort.Search(len(times), func(i int) bool { return times[i].After(cutoff) })
+	return float64(count) / o.updateRateWindow.Seconds()
+}
+
+// Must be called with the write lock held.
+func (o *Orderbooks) recordUpdateTime(fp pair.CurrencyPair, orderbookType string, updated time.Time) {
+	if o.updateRateWindow <= 0 {
+		return
+	}
+	key := historyKey{fp.FirstCurrency, fp.SecondCurrency, orderbookType}
+	times := o.updateTimes[key]
+	// Drop the updates that have fallen out of the window.
+	cutoff := updated.Add(-o.updateRateWindow)
+	expired := sort.Search(len(times), func(i int) bool { return times[i].After(cutoff) })
+	o.updateTimes[key] = append(times[expired:], updated)
+}
+
+// Must be called with the write lock held.
+func (o *Orderbooks) recordHistory(fp pair.CurrencyPair, orderbookType string, snapshot Base) {
+	if o.historyDepth <= 0 {
+		return
+	}
+	key := historyKey{fp.FirstCurrency, fp.SecondCurrency, orderbookType}
+	ring, ok := o.history[key]
+	if !ok {
+		ring = &snapshotRing{}
+		o.history[key] = ring
+	}
+	ring.add(snapshot.Clone(), o.historyDepth)
 }
 
 // Returns a new currency pair based on the given one that's formatted using the internal format.
@@ -155,7 +1072,7 @@ func (o *Orderbooks) formatCurrency(currency pair.CurrencyItem) pair.CurrencyIte
 // Init creates a new set of Orderbooks
 func Init() Orderbooks {
 	obs := Orderbooks{}
-	obs.m = sync.Mutex{}
+	obs.m = sync.RWMutex{}
 	obs.orderbooks = make(map[pair.CurrencyItem]map[pair.CurrencyItem]map[string]Base)
 	return obs
 }
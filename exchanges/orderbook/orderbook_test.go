@@ -1,6 +1,7 @@
 package orderbook
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -39,6 +40,32 @@ func TestCalculateTotaAsks(t *testing.T) {
 	}
 }
 
+func TestTotalNotional(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Bids: []Item{{Price: 100, Amount: 1}, {Price: 90, Amount: 3}},
+		Asks: []Item{{Price: 110, Amount: 2}},
+	}
+
+	if bids, asks := base.TotalNotional(); bids != 370 || asks != 220 {
+		t.Errorf("Test failed. TestTotalNotional expected 370 and 220, got %v and %v", bids, asks)
+	}
+}
+
+func TestAveragePrice(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Bids: []Item{{Price: 100, Amount: 1}, {Price: 90, Amount: 3}},
+	}
+
+	if avg, err := base.AverageBidPrice(); err != nil || avg != 92.5 {
+		t.Errorf("Test failed. TestAveragePrice expected 92.5, got %v %v", avg, err)
+	}
+	if _, err := base.AverageAskPrice(); err == nil {
+		t.Error("Test failed. TestAveragePrice expected an error for empty asks")
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	t.Parallel()
 	currency := pair.NewCurrencyPair("BTC", "USD")
@@ -71,6 +98,720 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestSanitizeLevels(t *testing.T) {
+	t.Parallel()
+	bids := []Item{{Price: 100, Amount: 1}, {Price: 99, Amount: 0}, {Price: 98, Amount: -1}, {Price: 97, Amount: 2}}
+	asks := []Item{{Price: 0, Amount: 1}, {Price: -101, Amount: 1}, {Price: 102, Amount: 3}}
+
+	base := Base{}
+	if dropped := base.Update(bids, asks); dropped != 4 {
+		t.Errorf("Test failed. TestSanitizeLevels expected 4 dropped levels, got %d", dropped)
+	}
+	if !reflect.DeepEqual(base.Bids, []Item{{Price: 100, Amount: 1}, {Price: 97, Amount: 2}}) ||
+		!reflect.DeepEqual(base.Asks, []Item{{Price: 102, Amount: 3}}) {
+		t.Errorf("Test failed. TestSanitizeLevels unexpected levels %v %v", base.Bids, base.Asks)
+	}
+	if bids[1].Amount != 0 || len(bids) != 4 {
+		t.Error("Test failed. TestSanitizeLevels modified the input levels")
+	}
+
+	obs := Init()
+	currency := pair.NewCurrencyPair("BTC", "USD")
+	var reported []int
+	obs.SetDroppedLevelsHandler(func(exchangeName string, p pair.CurrencyPair, orderbookType string,
+		droppedBids, droppedAsks int) {
+		if exchangeName != "test" || p != currency || orderbookType != Spot {
+			t.Errorf("Test failed. TestSanitizeLevels unexpected dropped levels report for %s %s %s",
+				exchangeName, p.Pair(), orderbookType)
+		}
+		reported = append(reported, droppedBids, droppedAsks)
+	})
+	if err := obs.ProcessOrderbook("test", currency, Base{Bids: bids, Asks: asks}, Spot); err != nil {
+		t.Fatalf("Test failed. TestSanitizeLevels ProcessOrderbook returned error: %s", err)
+	}
+	if !reflect.DeepEqual(reported, []int{2, 2}) {
+		t.Errorf("Test failed. TestSanitizeLevels reported dropped levels %v", reported)
+	}
+	obs.SetDroppedLevelsHandler(nil)
+	if err := obs.ProcessOrderbook("test", currency, Base{Bids: bids, Asks: asks}, Spot); err != nil ||
+		len(reported) != 2 {
+		t.Errorf("Test failed. TestSanitizeLevels reported dropped levels %v after the handler was removed", reported)
+	}
+	if result, _ := obs.LookupOrderbook(currency, Spot); len(result.Bids) != 2 || len(result.Asks) != 1 {
+		t.Errorf("Test failed. TestSanitizeLevels unexpected stored levels %v %v", result.Bids, result.Asks)
+	}
+	if err := obs.ProcessOrderbook("test", currency, Base{Bids: bids[1:3], Asks: asks[:2]}, Spot); err == nil {
+		t.Error("Test failed. TestSanitizeLevels expected an error when all levels are invalid")
+	}
+}
+
+func TestClone(t *testing.T) {
+	t.Parallel()
+	currency := pair.NewCurrencyPair("BTC", "USD")
+	base := Base{
+		Pair: currency,
+		Asks: []Item{{Price: 100, Amount: 10}},
+		Bids: []Item{{Price: 99, Amount: 10}},
+	}
+
+	clone := base.Clone()
+	clone.Asks[0].Amount = 1
+	clone.Bids = append(clone.Bids, Item{Price: 98, Amount: 1})
+	clone.Pair.FirstCurrency = "ETH"
+	if base.Asks[0].Amount != 10 || len(base.Bids) != 1 || base.Pair.FirstCurrency != "BTC" {
+		t.Error("Test failed. TestClone modifying the clone modified the original")
+	}
+
+	o := Init()
+	if err := o.ProcessOrderbook("Exchange", currency, base, Spot); err != nil {
+		t.Fatalf("Test failed. TestClone failed to process orderbook. Error %s", err)
+	}
+	result, _ := o.GetOrderbook("Exchange", currency, Spot)
+	result.Asks[0].Amount = 5
+	result, _ = o.GetOrderbook("Exchange", currency, Spot)
+	if result.Asks[0].Amount != 10 {
+		t.Error("Test failed. TestClone GetOrderbook returned the stored levels")
+	}
+}
+
+func TestGetBidsAsks(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 100, Amount: 10}},
+		Bids: []Item{{Price: 99, Amount: 10}},
+	}
+
+	bids, asks := base.GetBids(true), base.GetAsks(true)
+	if !reflect.DeepEqual(bids, base.Bids) || !reflect.DeepEqual(asks, base.Asks) {
+		t.Errorf("Test failed. TestGetBidsAsks returned %v %v", bids, asks)
+	}
+	bids[0].Amount, asks[0].Amount = 1, 1
+	if base.Bids[0].Amount != 10 || base.Asks[0].Amount != 10 {
+		t.Error("Test failed. TestGetBidsAsks modifying a copy modified the orderbook")
+	}
+
+	base.GetBids(false)[0].Amount = 2
+	base.GetAsks(false)[0].Amount = 3
+	if base.Bids[0].Amount != 2 || base.Asks[0].Amount != 3 {
+		t.Error("Test failed. TestGetBidsAsks expected the levels to be shared without copying")
+	}
+	if (&Base{}).GetBids(true) != nil {
+		t.Error("Test failed. TestGetBidsAsks expected nil bids for an empty orderbook")
+	}
+}
+
+func TestLookupOrderbook(t *testing.T) {
+	t.Parallel()
+	currency := pair.NewCurrencyPair("BTC", "USD")
+	base := Base{
+		Pair: currency,
+		Asks: []Item{{Price: 100, Amount: 10}},
+	}
+	o := Init()
+	if err := o.ProcessOrderbook("Exchange", currency, base, Spot); err != nil {
+		t.Fatalf("Test failed. TestLookupOrderbook failed to process orderbook. Error %s", err)
+	}
+
+	result, ok := o.LookupOrderbook(pair.NewCurrencyPair("btc", "usd"), Spot)
+	if !ok || len(result.Asks) != 1 {
+		t.Error("Test failed. TestLookupOrderbook failed to find orderbook")
+	}
+	if _, ok = o.LookupOrderbook(currency, "monthly"); ok {
+		t.Error("Test failed. TestLookupOrderbook found non-existent orderbook type")
+	}
+	if _, ok = o.LookupOrderbook(pair.NewCurrencyPair("BTC", "EUR"), Spot); ok {
+		t.Error("Test failed. TestLookupOrderbook found non-existent orderbook pair")
+	}
+}
+
+func TestDepthConversion(t *testing.T) {
+	t.Parallel()
+	currency := pair.NewCurrencyPair("BTC", "USD")
+	depth := Depth{
+		Bids: []Item{{Price: 99, Amount: 1}},
+		Asks: []Item{{Price: 100, Amount: 2}, {Price: 101, Amount: 3}},
+	}
+
+	base := depth.ToBase(currency)
+	if base.Pair != currency || base.CurrencyPair != currency.Pair().String() ||
+		len(base.Bids) != 1 || len(base.Asks) != 2 {
+		t.Fatalf("Test failed. TestDepthConversion ToBase returned %+v", base)
+	}
+	depth.Asks[0].Amount = 5
+	if base.Asks[0].Amount != 2 {
+		t.Error("Test failed. TestDepthConversion ToBase aliased the depth levels")
+	}
+
+	result := base.ToDepth()
+	if len(result.Bids) != 1 || result.Asks[1].Price != 101 {
+		t.Errorf("Test failed. TestDepthConversion ToDepth returned %+v", result)
+	}
+}
+
+func TestNewBaseFromTuples(t *testing.T) {
+	t.Parallel()
+	currency := pair.NewCurrencyPair("BTC", "USD")
+	base, err := NewBaseFromTuples(currency, [][]float64{{98, 1}, {99, 2}}, [][]float64{{102, 3}, {101, 4}})
+	if err != nil {
+		t.Fatalf("Test failed. TestNewBaseFromTuples returned error: %s", err)
+	}
+	if base.Pair != currency || base.CurrencyPair != currency.Pair().String() ||
+		!reflect.DeepEqual(base.Bids, []Item{{Price: 99, Amount: 2}, {Price: 98, Amount: 1}}) ||
+		!reflect.DeepEqual(base.Asks, []Item{{Price: 101, Amount: 4}, {Price: 102, Amount: 3}}) {
+		t.Errorf("Test failed. TestNewBaseFromTuples returned %+v", base)
+	}
+
+	if _, err = NewBaseFromTuples(currency, nil, [][]float64{{101, 1}, {102}}); err == nil {
+		t.Error("Test failed. TestNewBaseFromTuples expected an error for a malformed tuple")
+	}
+}
+
+func TestBinanceDepth(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Bids: []Item{{Price: 0.0042, Amount: 100}, {Price: 0.0041, Amount: 2.5}},
+		Asks: []Item{{Price: 0.0043, Amount: 12}},
+	}
+	data, err := base.MarshalBinanceDepth()
+	if err != nil {
+		t.Fatalf("Test failed. TestBinanceDepth MarshalBinanceDepth returned error: %s", err)
+	}
+	expected := `{"bids":[["0.00420000","100.00000000"],["0.00410000","2.50000000"]],` +
+		`"asks":[["0.00430000","12.00000000"]]}`
+	if string(data) != expected {
+		t.Errorf("Test failed. TestBinanceDepth MarshalBinanceDepth returned %s", data)
+	}
+
+	decoded := Base{}
+	if err = decoded.UnmarshalBinanceDepth(data); err != nil {
+		t.Fatalf("Test failed. TestBinanceDepth UnmarshalBinanceDepth returned error: %s", err)
+	}
+	if !reflect.DeepEqual(decoded.Bids, base.Bids) || !reflect.DeepEqual(decoded.Asks, base.Asks) {
+		t.Errorf("Test failed. TestBinanceDepth round trip returned %+v", decoded)
+	}
+	if err = decoded.UnmarshalBinanceDepth([]byte(`{"bids":[["1.0","2.0"]],"asks":[["3.0"]]}`)); err == nil {
+		t.Error("Test failed. TestBinanceDepth expected an error for a malformed level")
+	}
+	if err = decoded.UnmarshalBinanceDepth([]byte(`{"bids":[["x","2.0"]],"asks":[]}`)); err == nil {
+		t.Error("Test failed. TestBinanceDepth expected an error for an invalid price")
+	}
+	if !reflect.DeepEqual(decoded.Bids, base.Bids) {
+		t.Error("Test failed. TestBinanceDepth orderbook changed by a malformed payload")
+	}
+}
+
+func TestReplaceFrom(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 100, Amount: 10}},
+		Bids: []Item{{Price: 99, Amount: 10}},
+	}
+	snapshotTime := time.Now().Add(-time.Minute)
+	snapshot := Base{
+		Asks:        []Item{{Price: 101, Amount: 1}, {Price: 102, Amount: 2}},
+		Bids:        []Item{{Price: 98, Amount: 3}},
+		LastUpdated: snapshotTime,
+	}
+
+	base.ReplaceFrom(snapshot)
+	if len(base.Asks) != 2 || base.Bids[0].Price != 98 || !base.LastUpdated.Equal(snapshotTime) {
+		t.Fatal("Test failed. TestReplaceFrom didn't replace the book")
+	}
+
+	snapshot.Asks[0].Amount = 5
+	if base.Asks[0].Amount != 1 {
+		t.Error("Test failed. TestReplaceFrom aliased the snapshot levels")
+	}
+
+	base.ReplaceFrom(Base{})
+	if len(base.Asks) != 0 || base.LastUpdated.IsZero() {
+		t.Error("Test failed. TestReplaceFrom expected an empty book updated now")
+	}
+}
+
+func TestCumulativeLevels(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 100, Amount: 1}, {Price: 101, Amount: 2}, {Price: 102, Amount: 3}},
+		Bids: []Item{{Price: 99, Amount: 4}, {Price: 98, Amount: 5}},
+	}
+
+	asks := base.CumulativeAsks()
+	if len(asks) != 3 || asks[0].Amount != 1 || asks[1].Amount != 3 || asks[2].Amount != 6 ||
+		asks[2].Price != 102 {
+		t.Errorf("Test failed. TestCumulativeLevels CumulativeAsks returned %v", asks)
+	}
+	bids := base.CumulativeBids()
+	if len(bids) != 2 || bids[1].Amount != 9 || bids[1].Price != 98 {
+		t.Errorf("Test failed. TestCumulativeLevels CumulativeBids returned %v", bids)
+	}
+	if base.Asks[2].Amount != 3 {
+		t.Error("Test failed. TestCumulativeLevels modified the orderbook")
+	}
+}
+
+func TestVolumeToMovePrice(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 100, Amount: 1}, {Price: 101, Amount: 2}, {Price: 102, Amount: 3}},
+		Bids: []Item{{Price: 99, Amount: 1}, {Price: 98, Amount: 2}, {Price: 97, Amount: 3}},
+	}
+
+	volume, price, err := base.VolumeToMovePrice(SideBuy, 2)
+	if err != nil || volume != 3 || price != 102 {
+		t.Errorf("Test failed. TestVolumeToMovePrice buy returned %v, %v, %v", volume, price, err)
+	}
+
+	volume, price, err = base.VolumeToMovePrice(SideSell, 1)
+	if err != nil || volume != 1 || price != 98 {
+		t.Errorf("Test failed. TestVolumeToMovePrice sell returned %v, %v, %v", volume, price, err)
+	}
+
+	volume, _, err = base.VolumeToMovePrice(SideBuy, 10)
+	if err == nil || volume != 6 {
+		t.Error("Test failed. TestVolumeToMovePrice expected insufficient depth error")
+	}
+
+	if _, _, err = base.VolumeToMovePrice("bids", 1); err == nil {
+		t.Error("Test failed. TestVolumeToMovePrice accepted an invalid side")
+	}
+}
+
+func TestPriceAtCumulativeVolume(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 100, Amount: 1}, {Price: 101, Amount: 2}, {Price: 102, Amount: 3}},
+		Bids: []Item{{Price: 99, Amount: 1}, {Price: 98, Amount: 2}},
+	}
+
+	if price, ok := base.PriceAtCumulativeVolume(SideBuy, 2.5); !ok || price != 101 {
+		t.Errorf("Test failed. TestPriceAtCumulativeVolume buy returned %v, %v", price, ok)
+	}
+	if price, ok := base.PriceAtCumulativeVolume(SideSell, 1); !ok || price != 99 {
+		t.Errorf("Test failed. TestPriceAtCumulativeVolume sell returned %v, %v", price, ok)
+	}
+	if _, ok := base.PriceAtCumulativeVolume(SideSell, 4); ok {
+		t.Error("Test failed. TestPriceAtCumulativeVolume expected insufficient depth")
+	}
+	if _, ok := base.PriceAtCumulativeVolume("asks", 1); ok {
+		t.Error("Test failed. TestPriceAtCumulativeVolume accepted an invalid side")
+	}
+}
+
+func TestCanFillAtPrice(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 100, Amount: 1}, {Price: 200, Amount: 2}},
+		Bids: []Item{{Price: 50, Amount: 2}, {Price: 25, Amount: 4}},
+	}
+
+	for _, tc := range []struct {
+		side       string
+		quantity   float64
+		limitPrice float64
+		expected   bool
+	}{
+		{SideBuy, 1, 100, true},
+		{SideBuy, 1.5, 100, false},
+		{SideBuy, 3, 200, true},
+		{SideBuy, 3.5, 500, false},
+		{SideSell, 2, 50, true},
+		{SideSell, 2, 60, false},
+		{SideSell, 6, 25, true},
+		{"hold", 1, 100, false},
+	} {
+		if result := base.CanFillAtPrice(tc.side, tc.quantity, tc.limitPrice); result != tc.expected {
+			t.Errorf("Test failed. TestCanFillAtPrice %s %v at %v returned %v", tc.side, tc.quantity,
+				tc.limitPrice, result)
+		}
+	}
+}
+
+func TestNotionalToBase(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 100, Amount: 1}, {Price: 200, Amount: 2}},
+		Bids: []Item{{Price: 50, Amount: 2}, {Price: 25, Amount: 4}},
+	}
+
+	// 100 spent on the first level, 200 on the second
+	qty, avgPrice, err := base.NotionalToBaseBuy(300)
+	if err != nil || qty != 2 || avgPrice != 150 {
+		t.Errorf("Test failed. TestNotionalToBase NotionalToBaseBuy returned %v, %v, %v", qty, avgPrice, err)
+	}
+	// 100 received from the first level, 50 from the second
+	qty, avgPrice, err = base.NotionalToBaseSell(150)
+	if err != nil || qty != 4 || avgPrice != 37.5 {
+		t.Errorf("Test failed. TestNotionalToBase NotionalToBaseSell returned %v, %v, %v", qty, avgPrice, err)
+	}
+
+	qty, avgPrice, err = base.NotionalToBaseBuy(1000)
+	if err == nil || err.Error() != ErrInsufficientDepth || qty != 3 || avgPrice != 500.0/3 {
+		t.Errorf("Test failed. TestNotionalToBase expected insufficient depth, got %v, %v, %v", qty, avgPrice, err)
+	}
+	if _, _, err = (&Base{}).NotionalToBaseSell(1); err == nil || err.Error() != ErrOrderbookSideEmpty {
+		t.Errorf("Test failed. TestNotionalToBase expected empty side error, got %v", err)
+	}
+}
+
+func TestEffectiveSpread(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 101, Amount: 1}, {Price: 104, Amount: 3}},
+		Bids: []Item{{Price: 99, Amount: 2}, {Price: 96, Amount: 2}},
+	}
+
+	if price, err := base.VWAPBuy(2); err != nil || price != 102.5 {
+		t.Errorf("Test failed. TestEffectiveSpread VWAPBuy returned %v, %v", price, err)
+	}
+	if price, err := base.VWAPSell(4); err != nil || price != 97.5 {
+		t.Errorf("Test failed. TestEffectiveSpread VWAPSell returned %v, %v", price, err)
+	}
+	// quoted spread at the top of the book
+	if spread, err := base.EffectiveSpread(1); err != nil || spread != 2 {
+		t.Errorf("Test failed. TestEffectiveSpread returned %v, %v", spread, err)
+	}
+	// buy at (101 + 104 * 2) / 3, sell at (99 * 2 + 96) / 3
+	if spread, err := base.EffectiveSpread(3); err != nil || spread != 309.0/3-294.0/3 {
+		t.Errorf("Test failed. TestEffectiveSpread returned %v, %v", spread, err)
+	}
+	// enough asks but not enough bids
+	if _, err := base.EffectiveSpread(4.5); err == nil || err.Error() != ErrInsufficientDepth {
+		t.Errorf("Test failed. TestEffectiveSpread expected insufficient depth, got %v", err)
+	}
+	if _, err := base.EffectiveSpread(0); err == nil {
+		t.Error("Test failed. TestEffectiveSpread expected an error for a zero size")
+	}
+	base.Bids = nil
+	if _, err := base.EffectiveSpread(1); err == nil || err.Error() != ErrOrderbookSideEmpty {
+		t.Errorf("Test failed. TestEffectiveSpread expected empty side error, got %v", err)
+	}
+}
+
+func TestTopOfBook(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Bids: []Item{{Price: 99, Amount: 1}, {Price: 98, Amount: 2}},
+		Asks: []Item{{Price: 101, Amount: 3}},
+	}
+
+	bidPrice, bidQty, askPrice, askQty, ok := base.TopOfBook()
+	if !ok || bidPrice != 99 || bidQty != 1 || askPrice != 101 || askQty != 3 {
+		t.Errorf("Test failed. TestTopOfBook returned %v %v %v %v %v", bidPrice, bidQty, askPrice, askQty, ok)
+	}
+	base.Asks = nil
+	if _, _, _, _, ok = base.TopOfBook(); ok {
+		t.Error("Test failed. TestTopOfBook expected ok to be false with no asks")
+	}
+}
+
+func TestSlippageVsMid(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 101, Amount: 1}},
+		Bids: []Item{{Price: 99, Amount: 1}},
+	}
+
+	if slippage, err := base.SlippageVsMid(SideBuy, 101); err != nil || slippage != 100 {
+		t.Errorf("Test failed. TestSlippageVsMid buy returned %v, %v", slippage, err)
+	}
+	if slippage, err := base.SlippageVsMid(SideSell, 99); err != nil || slippage != 100 {
+		t.Errorf("Test failed. TestSlippageVsMid sell returned %v, %v", slippage, err)
+	}
+	if slippage, err := base.SlippageVsMid(SideSell, 102); err != nil || slippage != -200 {
+		t.Errorf("Test failed. TestSlippageVsMid price improvement returned %v, %v", slippage, err)
+	}
+	if _, err := base.SlippageVsMid("hold", 100); err == nil || err.Error() != ErrInvalidSide {
+		t.Errorf("Test failed. TestSlippageVsMid expected invalid side error, got %v", err)
+	}
+	base.Bids = nil
+	if _, err := base.SlippageVsMid(SideBuy, 100); err == nil || err.Error() != ErrOrderbookSideEmpty {
+		t.Errorf("Test failed. TestSlippageVsMid expected empty side error, got %v", err)
+	}
+}
+
+func TestLevelsWithDistance(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 101, Amount: 1}, {Price: 110, Amount: 2}},
+		Bids: []Item{{Price: 99, Amount: 3}, {Price: 95, Amount: 4}},
+	}
+
+	bids, asks, err := base.LevelsWithDistance()
+	if err != nil {
+		t.Fatalf("Test failed. TestLevelsWithDistance returned error: %s", err)
+	}
+	expectedBids := []LevelDistance{{Price: 99, Amount: 3, PercentFromMid: 1}, {Price: 95, Amount: 4, PercentFromMid: 5}}
+	expectedAsks := []LevelDistance{{Price: 101, Amount: 1, PercentFromMid: 1}, {Price: 110, Amount: 2, PercentFromMid: 10}}
+	if !reflect.DeepEqual(bids, expectedBids) || !reflect.DeepEqual(asks, expectedAsks) {
+		t.Errorf("Test failed. TestLevelsWithDistance returned %v, %v", bids, asks)
+	}
+
+	base.Asks = nil
+	if _, _, err = base.LevelsWithDistance(); err == nil || err.Error() != ErrOrderbookSideEmpty {
+		t.Errorf("Test failed. TestLevelsWithDistance expected empty side error, got %v", err)
+	}
+	base.Bids = nil
+	if _, _, err = base.LevelsWithDistance(); err == nil || err.Error() != ErrOrderbookEmpty {
+		t.Errorf("Test failed. TestLevelsWithDistance expected empty orderbook error, got %v", err)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Bids: []Item{{Price: 98, Amount: 2}, {Price: 97, Amount: 0}, {Price: 99, Amount: 1}, {Price: 96, Amount: -1}},
+		Asks: []Item{{Price: 101, Amount: 0}, {Price: 102, Amount: 3}, {Price: 103, Amount: 4}},
+	}
+
+	base.Compact()
+	expectedBids := []Item{{Price: 99, Amount: 1}, {Price: 98, Amount: 2}}
+	expectedAsks := []Item{{Price: 102, Amount: 3}, {Price: 103, Amount: 4}}
+	if !reflect.DeepEqual(base.Bids, expectedBids) || !reflect.DeepEqual(base.Asks, expectedAsks) {
+		t.Errorf("Test failed. TestCompact returned %v %v", base.Bids, base.Asks)
+	}
+
+	empty := Base{Bids: []Item{{Price: 99, Amount: 0}}}
+	empty.Compact()
+	if len(empty.Bids) != 0 || len(empty.Asks) != 0 {
+		t.Errorf("Test failed. TestCompact expected an empty orderbook, got %v %v", empty.Bids, empty.Asks)
+	}
+}
+
+func TestApplyDelta(t *testing.T) {
+	t.Parallel()
+	base := Base{
+		Asks: []Item{{Price: 100, Amount: 1}, {Price: 102, Amount: 3}},
+		Bids: []Item{{Price: 99, Amount: 1}, {Price: 97, Amount: 3}},
+	}
+
+	base.ApplyDelta(
+		[]Item{{Price: 99, Amount: 0}, {Price: 98, Amount: 2}, {Price: 96, Amount: 0}},
+		[]Item{{Price: 101, Amount: 2}, {Price: 102, Amount: 5}, {Price: 103, Amount: 4}},
+	)
+
+	expectedBids := []Item{{Price: 98, Amount: 2}, {Price: 97, Amount: 3}}
+	expectedAsks := []Item{{Price: 100, Amount: 1}, {Price: 101, Amount: 2}, {Price: 102, Amount: 5},
+		{Price: 103, Amount: 4}}
+	if !reflect.DeepEqual(base.Bids, expectedBids) {
+		t.Errorf("Test failed. TestApplyDelta bids are %v", base.Bids)
+	}
+	if !reflect.DeepEqual(base.Asks, expectedAsks) {
+		t.Errorf("Test failed. TestApplyDelta asks are %v", base.Asks)
+	}
+	if base.LastUpdated.IsZero() {
+		t.Error("Test failed. TestApplyDelta didn't set LastUpdated")
+	}
+}
+
+func TestOldestNewestUpdate(t *testing.T) {
+	t.Parallel()
+	obs := Init()
+	if p, updated := obs.OldestUpdate(); p != (pair.CurrencyPair{}) || !updated.IsZero() {
+		t.Errorf("Test failed. TestOldestNewestUpdate expected zero values, got %v %v", p, updated)
+	}
+
+	base := Base{Asks: []Item{{Price: 100, Amount: 10}}}
+	obs.ProcessOrderbook("", pair.NewCurrencyPair("BTC", "USD"), base, Spot)
+	time.Sleep(time.Millisecond)
+	obs.ProcessOrderbook("", pair.NewCurrencyPair("ETH", "BTC"), base, Spot)
+	time.Sleep(time.Millisecond)
+	obs.ProcessOrderbook("", pair.NewCurrencyPair("LTC", "BTC"), base, Spot)
+
+	if p, _ := obs.OldestUpdate(); !p.Equal(pair.NewCurrencyPair("BTC", "USD")) {
+		t.Errorf("Test failed. TestOldestNewestUpdate unexpected oldest pair %v", p)
+	}
+	if p, _ := obs.NewestUpdate(); !p.Equal(pair.NewCurrencyPair("LTC", "BTC")) {
+		t.Errorf("Test failed. TestOldestNewestUpdate unexpected newest pair %v", p)
+	}
+}
+
+func TestGetAllOrderbooks(t *testing.T) {
+	t.Parallel()
+	obs := Init()
+	if pairs, books := obs.GetTrackedPairs(), obs.GetAllOrderbooks(); len(pairs) != 0 || len(books) != 0 {
+		t.Errorf("Test failed. TestGetAllOrderbooks expected an empty store, got %v %v", pairs, books)
+	}
+
+	base := Base{Asks: []Item{{Price: 100, Amount: 10}}}
+	obs.ProcessOrderbook("", pair.NewCurrencyPair("LTC", "BTC"), base, Spot)
+	obs.ProcessOrderbook("", pair.NewCurrencyPair("BTC", "USD"), base, "futures")
+	obs.ProcessOrderbook("", pair.NewCurrencyPair("ETH", "BTC"), base, Spot)
+	obs.ProcessOrderbook("", pair.NewCurrencyPair("BTC", "USD"), base, Spot)
+	obs.ProcessOrderbook("", pair.NewCurrencyPair("BTC", "EUR"), base, Spot)
+
+	expectedPairs := []string{"btc/eur", "btc/usd", "eth/btc", "ltc/btc"}
+	pairs := obs.GetTrackedPairs()
+	if len(pairs) != len(expectedPairs) {
+		t.Fatalf("Test failed. TestGetAllOrderbooks unexpected pairs %v", pairs)
+	}
+	for i, p := range pairs {
+		if p.Pair().String() != expectedPairs[i] {
+			t.Errorf("Test failed. TestGetAllOrderbooks unexpected pair %d: %s", i, p.Pair())
+		}
+	}
+
+	expectedBooks := []string{"btc/eur SPOT", "btc/usd SPOT", "btc/usd futures", "eth/btc SPOT", "ltc/btc SPOT"}
+	books := obs.GetAllOrderbooks()
+	if len(books) != len(expectedBooks) {
+		t.Fatalf("Test failed. TestGetAllOrderbooks unexpected orderbooks %v", books)
+	}
+	for i, book := range books {
+		if book.CurrencyPair+" "+book.Type != expectedBooks[i] || len(book.Asks) != 1 {
+			t.Errorf("Test failed. TestGetAllOrderbooks unexpected orderbook %d: %+v", i, book)
+		}
+	}
+	books[0].Asks[0].Price = 1
+	if obs.GetAllOrderbooks()[0].Asks[0].Price != 100 {
+		t.Error("Test failed. TestGetAllOrderbooks orderbook was modified via a returned copy")
+	}
+}
+
+func TestBestAcrossTypes(t *testing.T) {
+	t.Parallel()
+	obs := Init()
+	currency := pair.NewCurrencyPair("BTC", "USD")
+	obs.ProcessOrderbook("", currency, Base{
+		Bids: []Item{{Price: 99, Amount: 1}, {Price: 98, Amount: 2}},
+		Asks: []Item{{Price: 102, Amount: 1}},
+	}, Spot)
+	obs.ProcessOrderbook("", currency, Base{
+		Bids: []Item{{Price: 98.5, Amount: 3}},
+		Asks: []Item{{Price: 101, Amount: 4}},
+	}, "AGGREGATED")
+
+	bid, ask, err := obs.BestAcrossTypes(currency, []string{Spot, "AGGREGATED", "MISSING"})
+	if err != nil || bid != (Item{Price: 99, Amount: 1}) || ask != (Item{Price: 101, Amount: 4}) {
+		t.Errorf("Test failed. TestBestAcrossTypes returned %v %v %v", bid, ask, err)
+	}
+	if _, _, err = obs.BestAcrossTypes(currency, []string{"MISSING"}); err == nil {
+		t.Error("Test failed. TestBestAcrossTypes expected an error for missing orderbook types")
+	}
+	if _, _, err = obs.BestAcrossTypes(pair.NewCurrencyPair("ETH", "USD"), []string{Spot}); err == nil {
+		t.Error("Test failed. TestBestAcrossTypes expected an error for a missing currency pair")
+	}
+}
+
+func TestBestFillAcrossTypes(t *testing.T) {
+	t.Parallel()
+	obs := Init()
+	currency := pair.NewCurrencyPair("BTC", "USD")
+	obs.ProcessOrderbook("", currency, Base{
+		Bids: []Item{{Price: 99, Amount: 1}, {Price: 97, Amount: 2}},
+		Asks: []Item{{Price: 100, Amount: 1}, {Price: 103, Amount: 2}},
+	}, Spot)
+	obs.ProcessOrderbook("", currency, Base{
+		Bids: []Item{{Price: 98, Amount: 3}},
+		Asks: []Item{{Price: 101, Amount: 2}},
+	}, "AGGREGATED")
+	types := []string{Spot, "AGGREGATED", "MISSING"}
+
+	avgPrice, perBook, err := obs.BestFillAcrossTypes(currency, SideBuy, 4, types)
+	if err != nil || avgPrice != 101.25 || !reflect.DeepEqual(perBook, map[string]float64{Spot: 2, "AGGREGATED": 2}) {
+		t.Errorf("Test failed. TestBestFillAcrossTypes buy returned %v %v %v", avgPrice, perBook, err)
+	}
+	avgPrice, perBook, err = obs.BestFillAcrossTypes(currency, SideSell, 2, types)
+	if err != nil || avgPrice != 98.5 || !reflect.DeepEqual(perBook, map[string]float64{Spot: 1, "AGGREGATED": 1}) {
+		t.Errorf("Test failed. TestBestFillAcrossTypes sell returned %v %v %v", avgPrice, perBook, err)
+	}
+	avgPrice, perBook, err = obs.BestFillAcrossTypes(currency, SideSell, 10, types)
+	if err == nil || err.Error() != ErrInsufficientDepth || avgPrice != 587.0/6 ||
+		!reflect.DeepEqual(perBook, map[string]float64{Spot: 3, "AGGREGATED": 3}) {
+		t.Errorf("Test failed. TestBestFillAcrossTypes expected insufficient depth, got %v %v %v", avgPrice,
+			perBook, err)
+	}
+
+	if _, _, err = obs.BestFillAcrossTypes(currency, "hold", 1, types); err == nil || err.Error() != ErrInvalidSide {
+		t.Errorf("Test failed. TestBestFillAcrossTypes expected invalid side error, got %v", err)
+	}
+	if _, _, err = obs.BestFillAcrossTypes(currency, SideBuy, 0, types); err == nil {
+		t.Error("Test failed. TestBestFillAcrossTypes expected an error for a zero quantity")
+	}
+	if _, _, err = obs.BestFillAcrossTypes(currency, SideBuy, 1, []string{"MISSING"}); err == nil ||
+		err.Error() != ErrOrderbookNotFound {
+		t.Errorf("Test failed. TestBestFillAcrossTypes expected orderbook not found error, got %v", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+	obs := Init()
+	currency := pair.NewCurrencyPair("BTC", "USD")
+	process := func(price float64) {
+		obs.ProcessOrderbook("", currency, Base{Bids: []Item{{Price: price, Amount: 1}}}, Spot)
+	}
+
+	process(1)
+	if history := obs.GetHistory(currency, Spot); history != nil {
+		t.Errorf("Test failed. TestHistory expected no history by default, got %v", history)
+	}
+
+	obs.SetHistoryDepth(3)
+	for price := 2.0; price <= 6; price++ {
+		process(price)
+	}
+	history := obs.GetHistory(pair.NewCurrencyPair("btc", "usd"), Spot)
+	if len(history) != 3 {
+		t.Fatalf("Test failed. TestHistory expected 3 snapshots, got %d", len(history))
+	}
+	for i, snapshot := range history {
+		if snapshot.Bids[0].Price != float64(i+4) || snapshot.LastUpdated.IsZero() {
+			t.Errorf("Test failed. TestHistory unexpected snapshot %d: %+v", i, snapshot)
+		}
+	}
+	history[0].Bids[0].Price = 100
+	if obs.GetHistory(currency, Spot)[0].Bids[0].Price != 4 {
+		t.Error("Test failed. TestHistory snapshots were modified via a returned copy")
+	}
+	if history = obs.GetHistory(currency, "futures"); history != nil {
+		t.Errorf("Test failed. TestHistory unexpected history %v", history)
+	}
+
+	obs.SetHistoryDepth(0)
+	process(7)
+	if history = obs.GetHistory(currency, Spot); history != nil {
+		t.Errorf("Test failed. TestHistory expected history to be discarded, got %v", history)
+	}
+}
+
+func TestUpdateRate(t *testing.T) {
+	t.Parallel()
+	obs := Init()
+	currency := pair.NewCurrencyPair("BTC", "USD")
+	base := Base{Bids: []Item{{Price: 100, Amount: 1}}}
+
+	obs.ProcessOrderbook("", currency, base, Spot)
+	if rate := obs.UpdateRate(currency, Spot); rate != 0 {
+		t.Errorf("Test failed. TestUpdateRate expected no rate by default, got %v", rate)
+	}
+
+	obs.SetUpdateRateWindow(200 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		obs.ProcessOrderbook("", currency, base, Spot)
+	}
+	obs.ProcessOrderbook("", currency, base, "futures")
+	if rate := obs.UpdateRate(pair.NewCurrencyPair("btc", "usd"), Spot); rate != 25 {
+		t.Errorf("Test failed. TestUpdateRate expected 25 updates per second, got %v", rate)
+	}
+	if rate := obs.UpdateRate(currency, "futures"); rate != 5 {
+		t.Errorf("Test failed. TestUpdateRate expected 5 updates per second, got %v", rate)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	obs.ProcessOrderbook("", currency, base, Spot)
+	if rate := obs.UpdateRate(currency, Spot); rate != 5 {
+		t.Errorf("Test failed. TestUpdateRate expected expired updates to be dropped, got %v", rate)
+	}
+	if rate := obs.UpdateRate(currency, "futures"); rate != 0 {
+		t.Errorf("Test failed. TestUpdateRate expected a stalled feed to have no updates, got %v", rate)
+	}
+
+	obs.SetUpdateRateWindow(0)
+	if rate := obs.UpdateRate(currency, Spot); rate != 0 {
+		t.Errorf("Test failed. TestUpdateRate expected tracking to be disabled, got %v", rate)
+	}
+}
+
 func TestGetOrderbook(t *testing.T) {
 	currency := pair.NewCurrencyPair("BTC", "USD")
 	base := Base{
@@ -81,7 +822,9 @@ func TestGetOrderbook(t *testing.T) {
 	}
 
 	o := Init()
-	o.CreateNewOrderbook("Exchange", currency, base, Spot)
+	if err := o.ProcessOrderbook("Exchange", currency, base, Spot); err != nil {
+		t.Fatalf("Test failed. TestGetOrderbook failed to process orderbook. Error %s", err)
+	}
 
 	result, err := o.GetOrderbook("Exchange", currency, Spot)
 	if err != nil {
@@ -93,11 +836,6 @@ func TestGetOrderbook(t *testing.T) {
 		t.Fatal("Test failed. TestGetOrderbook failed. Mismatched pairs")
 	}
 
-	_, err = o.GetOrderbook("nonexistent", currency, Spot)
-	if err == nil {
-		t.Fatal("Test failed. TestGetOrderbook retrieved non-existent orderbook")
-	}
-
 	currency.FirstCurrency = "blah"
 	_, err = o.GetOrderbook("Exchange", currency, Spot)
 	if err == nil {
@@ -111,7 +849,7 @@ func TestGetOrderbook(t *testing.T) {
 	}
 }
 
-func TestGetOrderbookByExchange(t *testing.T) {
+func TestGetAllOrderbooksSingle(t *testing.T) {
 	currency := pair.NewCurrencyPair("BTC", "USD")
 	base := Base{
 		Pair:         currency,
@@ -121,17 +859,16 @@ func TestGetOrderbookByExchange(t *testing.T) {
 	}
 
 	o := Init()
-	o.CreateNewOrderbook("Exchange", currency, base, Spot)
-
-	_, err := o.GetOrderbookByExchange("Exchange")
-	if err != nil {
-		t.Fatalf("Test failed. TestGetOrderbookByExchange failed to get orderbook. Error %s",
-			err)
+	if all := o.GetAllOrderbooks(); len(all) != 0 {
+		t.Fatalf("Test failed. TestGetAllOrderbooksSingle retrieved orderbooks from an empty store %v", all)
+	}
+	if err := o.ProcessOrderbook("Exchange", currency, base, Spot); err != nil {
+		t.Fatalf("Test failed. TestGetAllOrderbooksSingle failed to process orderbook. Error %s", err)
 	}
 
-	_, err = o.GetOrderbookByExchange("nonexistant")
-	if err == nil {
-		t.Fatal("Test failed. TestGetOrderbookByExchange retrieved non-existant orderbook")
+	all := o.GetAllOrderbooks()
+	if len(all) != 1 || all[0].Type != Spot || all[0].Pair.Pair() != currency.Pair() {
+		t.Fatalf("Test failed. TestGetAllOrderbooksSingle unexpected orderbooks %v", all)
 	}
 }
 
@@ -145,14 +882,16 @@ func TestFirstCurrencyExists(t *testing.T) {
 	}
 
 	o := Init()
-	o.CreateNewOrderbook("Exchange", currency, base, Spot)
+	if err := o.ProcessOrderbook("Exchange", currency, base, Spot); err != nil {
+		t.Fatalf("Test failed. TestFirstCurrencyExists failed to process orderbook. Error %s", err)
+	}
 
-	if !o.FirstCurrencyExists("Exchange", currency.FirstCurrency) {
+	if !o.FirstCurrencyExists(currency.FirstCurrency) {
 		t.Fatal("Test failed. TestFirstCurrencyExists expected first currency doesn't exist")
 	}
 
 	var item pair.CurrencyItem = "blah"
-	if o.FirstCurrencyExists("Exchange", item) {
+	if o.FirstCurrencyExists(item) {
 		t.Fatal("Test failed. TestFirstCurrencyExists unexpected first currency exists")
 	}
 }
@@ -167,19 +906,21 @@ func TestSecondCurrencyExists(t *testing.T) {
 	}
 
 	o := Init()
-	o.CreateNewOrderbook("Exchange", currency, base, Spot)
+	if err := o.ProcessOrderbook("Exchange", currency, base, Spot); err != nil {
+		t.Fatalf("Test failed. TestSecondCurrencyExists failed to process orderbook. Error %s", err)
+	}
 
-	if !o.SecondCurrencyExists("Exchange", currency) {
+	if !o.SecondCurrencyExists(currency) {
 		t.Fatal("Test failed. TestSecondCurrencyExists expected first currency doesn't exist")
 	}
 
 	currency.SecondCurrency = "blah"
-	if o.SecondCurrencyExists("Exchange", currency) {
+	if o.SecondCurrencyExists(currency) {
 		t.Fatal("Test failed. TestSecondCurrencyExists unexpected first currency exists")
 	}
 }
 
-func TestCreateNewOrderbook(t *testing.T) {
+func TestProcessOrderbookNewOrderbook(t *testing.T) {
 	currency := pair.NewCurrencyPair("BTC", "USD")
 	base := Base{
 		Pair:         currency,
@@ -189,25 +930,49 @@ func TestCreateNewOrderbook(t *testing.T) {
 	}
 
 	o := Init()
-	o.CreateNewOrderbook("Exchange", currency, base, Spot)
+	if err := o.ProcessOrderbook("Exchange", currency, base, Spot); err != nil {
+		t.Fatalf("Test failed. TestProcessOrderbookNewOrderbook failed to process orderbook. Error %s", err)
+	}
 
 	result, err := o.GetOrderbook("Exchange", currency, Spot)
 	if err != nil {
-		t.Fatal("Test failed. TestCreateNewOrderbook failed to create new orderbook")
+		t.Fatal("Test failed. TestProcessOrderbookNewOrderbook failed to create new orderbook")
 	}
 
 	if result.Pair.Pair() != currency.Pair() {
-		t.Fatal("Test failed. TestCreateNewOrderbook result pair is incorrect")
+		t.Fatal("Test failed. TestProcessOrderbookNewOrderbook result pair is incorrect")
 	}
 
 	a, b := result.CalculateTotalAsks()
-	if a != 10 && b != 1000 {
-		t.Fatal("Test failed. TestCreateNewOrderbook CalculateTotalAsks value is incorrect")
+	if a != 10 || b != 1000 {
+		t.Fatal("Test failed. TestProcessOrderbookNewOrderbook CalculateTotalAsks value is incorrect")
 	}
 
 	a, b = result.CalculateTotalBids()
-	if a != 10 && b != 2000 {
-		t.Fatal("Test failed. TestCreateNewOrderbook CalculateTotalBids value is incorrect")
+	if a != 10 || b != 2000 {
+		t.Fatal("Test failed. TestProcessOrderbookNewOrderbook CalculateTotalBids value is incorrect")
+	}
+}
+
+func TestProcessOrderbookRejectsInvalidInput(t *testing.T) {
+	t.Parallel()
+	o := Init()
+	base := Base{Asks: []Item{{Price: 100, Amount: 10}}}
+
+	err := o.ProcessOrderbook("Exchange", pair.CurrencyPair{}, base, Spot)
+	if err == nil || err.Error() != ErrInvalidCurrencyPair {
+		t.Errorf("Test failed. TestProcessOrderbookRejectsInvalidInput expected invalid pair error, got %v", err)
+	}
+	err = o.ProcessOrderbook("Exchange", pair.NewCurrencyPair("BTC", ""), base, Spot)
+	if err == nil || err.Error() != ErrInvalidCurrencyPair {
+		t.Errorf("Test failed. TestProcessOrderbookRejectsInvalidInput expected invalid pair error, got %v", err)
+	}
+	err = o.ProcessOrderbook("Exchange", pair.NewCurrencyPair("BTC", "USD"), Base{}, Spot)
+	if err == nil || err.Error() != ErrOrderbookEmpty {
+		t.Errorf("Test failed. TestProcessOrderbookRejectsInvalidInput expected empty orderbook error, got %v", err)
+	}
+	if _, ok := o.LookupOrderbook(pair.NewCurrencyPair("BTC", "USD"), Spot); ok {
+		t.Error("Test failed. TestProcessOrderbookRejectsInvalidInput stored an empty orderbook")
 	}
 }
 
@@ -222,7 +987,9 @@ func TestProcessOrderbook(t *testing.T) {
 		Bids:         []Item{Item{Price: 200, Amount: 10}},
 	}
 
-	o.ProcessOrderbook("Exchange", currency, base, Spot)
+	if err := o.ProcessOrderbook("Exchange", currency, base, Spot); err != nil {
+		t.Fatalf("Test failed. TestProcessOrderbook failed to process orderbook. Error %s", err)
+	}
 
 	result, err := o.GetOrderbook("Exchange", currency, Spot)
 	if err != nil {
@@ -235,7 +1002,9 @@ func TestProcessOrderbook(t *testing.T) {
 
 	currency = pair.NewCurrencyPair("BTC", "GBP")
 	base.Pair = currency
-	o.ProcessOrderbook("Exchange", currency, base, Spot)
+	if err := o.ProcessOrderbook("Exchange", currency, base, Spot); err != nil {
+		t.Fatalf("Test failed. TestProcessOrderbook failed to process orderbook. Error %s", err)
+	}
 
 	result, err = o.GetOrderbook("Exchange", currency, Spot)
 	if err != nil {
@@ -247,7 +1016,9 @@ func TestProcessOrderbook(t *testing.T) {
 	}
 
 	base.Asks = []Item{Item{Price: 200, Amount: 200}}
-	o.ProcessOrderbook("Exchange", currency, base, "monthly")
+	if err := o.ProcessOrderbook("Exchange", currency, base, "monthly"); err != nil {
+		t.Fatalf("Test failed. TestProcessOrderbook failed to process orderbook. Error %s", err)
+	}
 
 	result, err = o.GetOrderbook("Exchange", currency, "monthly")
 	if err != nil {
@@ -255,18 +1026,21 @@ func TestProcessOrderbook(t *testing.T) {
 	}
 
 	a, b := result.CalculateTotalAsks()
-	if a != 200 && b != 40000 {
+	if a != 200 || b != 40000 {
 		t.Fatal("Test failed. TestProcessOrderbook CalculateTotalsAsks incorrect values")
 	}
 
 	base.Bids = []Item{Item{Price: 420, Amount: 200}}
-	o.ProcessOrderbook("Blah", currency, base, "quarterly")
+	if err := o.ProcessOrderbook("Blah", currency, base, "quarterly"); err != nil {
+		t.Fatalf("Test failed. TestProcessOrderbook failed to process orderbook. Error %s", err)
+	}
 	result, err = o.GetOrderbook("Blah", currency, "quarterly")
 	if err != nil {
 		t.Fatal("Test failed. TestProcessOrderbook failed to create new orderbook")
 	}
 
-	if a != 200 && b != 84000 {
+	a, b = result.CalculateTotalBids()
+	if a != 200 || b != 84000 {
 		t.Fatal("Test failed. TestProcessOrderbook CalculateTotalsBids incorrect values")
 	}
 }
@@ -101,7 +101,9 @@ func (p *Poloniex) UpdateOrderbook(currencyPair pair.CurrencyPair, assetType str
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Amount: data.Amount, Price: data.Price})
 	}
 
-	p.Orderbooks.ProcessOrderbook(p.GetName(), currencyPair, orderBook, assetType)
+	if err := p.Orderbooks.ProcessOrderbook(p.GetName(), currencyPair, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return p.Orderbooks.GetOrderbook(p.Name, currencyPair, assetType)
 }
 
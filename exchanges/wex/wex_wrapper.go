@@ -88,7 +88,9 @@ func (w *WEX) UpdateOrderbook(p pair.CurrencyPair, assetType string) (orderbook.
 		orderBook.Asks = append(orderBook.Asks, orderbook.Item{Price: data[0], Amount: data[1]})
 	}
 
-	w.Orderbooks.ProcessOrderbook(w.GetName(), p, orderBook, assetType)
+	if err := w.Orderbooks.ProcessOrderbook(w.GetName(), p, orderBook, assetType); err != nil {
+		return orderBook, err
+	}
 	return w.Orderbooks.GetOrderbook(w.Name, p, assetType)
 }
 